@@ -0,0 +1,17 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package avro_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestAvro(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Avro Suite")
+}
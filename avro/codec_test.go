@@ -0,0 +1,125 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package avro_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Encode and Decode", func() {
+	var schema *avro.Schema
+
+	BeforeEach(func() {
+		var err error
+		schema, err = avro.ParseSchema([]byte(changeRecordSchema))
+		Expect(err).To(BeNil())
+	})
+
+	It("decodes encoded record", func() {
+		buf := &bytes.Buffer{}
+		err := avro.Encode(schema, buf, map[string]interface{}{
+			"domain":       0,
+			"server_id":    1,
+			"sequence":     58,
+			"event_number": 1,
+			"timestamp":    1541348151,
+			"event_type":   "insert",
+			"id":           4,
+			"name":         "Hello",
+		})
+		Expect(err).To(BeNil())
+		value, err := avro.Decode(schema, bytes.NewReader(buf.Bytes()))
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal(map[string]interface{}{
+			"domain":       int32(0),
+			"server_id":    int32(1),
+			"sequence":     int32(58),
+			"event_number": int32(1),
+			"timestamp":    int32(1541348151),
+			"event_type":   "insert",
+			"id":           int32(4),
+			"name":         "Hello",
+		}))
+	})
+
+	It("encodes json numbers and null", func() {
+		buf := &bytes.Buffer{}
+		err := avro.Encode(schema, buf, map[string]interface{}{
+			"domain":       json.Number("0"),
+			"server_id":    json.Number("1"),
+			"sequence":     json.Number("58"),
+			"event_number": json.Number("1"),
+			"timestamp":    json.Number("1541348151"),
+			"event_type":   "delete",
+			"id":           json.Number("4"),
+			"name":         nil,
+		})
+		Expect(err).To(BeNil())
+		value, err := avro.Decode(schema, bytes.NewReader(buf.Bytes()))
+		Expect(err).To(BeNil())
+		Expect(value).To(HaveKeyWithValue("name", BeNil()))
+		Expect(value).To(HaveKeyWithValue("event_type", "delete"))
+	})
+
+	It("returns error for unknown enum symbol", func() {
+		err := avro.Encode(schema, &bytes.Buffer{}, map[string]interface{}{
+			"domain":       0,
+			"server_id":    1,
+			"sequence":     58,
+			"event_number": 1,
+			"timestamp":    1541348151,
+			"event_type":   "banana",
+			"id":           4,
+		})
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("decodes complex types", func() {
+		complexSchema, err := avro.ParseSchema([]byte(`{"type":"record","name":"r","fields":[
+			{"name":"l","type":"long"},
+			{"name":"f","type":"float"},
+			{"name":"d","type":"double"},
+			{"name":"b","type":"boolean"},
+			{"name":"raw","type":"bytes"},
+			{"name":"a","type":{"type":"array","items":"string"}},
+			{"name":"m","type":{"type":"map","values":"int"}}
+		]}`))
+		Expect(err).To(BeNil())
+		input := map[string]interface{}{
+			"l":   int64(-1234567890123),
+			"f":   float32(1.5),
+			"d":   float64(2.25),
+			"b":   true,
+			"raw": []byte{1, 2, 3},
+			"a":   []interface{}{"x", "y"},
+			"m":   map[string]interface{}{"k": int32(7)},
+		}
+		buf := &bytes.Buffer{}
+		Expect(avro.Encode(complexSchema, buf, input)).To(BeNil())
+		value, err := avro.Decode(complexSchema, bytes.NewReader(buf.Bytes()))
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal(input))
+	})
+
+	It("returns error on truncated data", func() {
+		buf := &bytes.Buffer{}
+		Expect(avro.Encode(&avro.Schema{Type: avro.TypeString}, buf, "hello")).To(BeNil())
+		_, err := avro.Decode(&avro.Schema{Type: avro.TypeString}, bytes.NewReader(buf.Bytes()[:3]))
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error on oversized length", func() {
+		buf := make([]byte, binary.MaxVarintLen64)
+		n := binary.PutVarint(buf, 1<<40)
+		_, err := avro.Decode(&avro.Schema{Type: avro.TypeBytes}, bytes.NewReader(buf[:n]))
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,233 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package avro
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// Magic bytes at the start of every Avro object container
+var Magic = []byte{'O', 'b', 'j', 1}
+
+const syncLength = 16
+
+// ContainerReader reads the records of Avro object containers from a stream.
+// https://avro.apache.org/docs/1.8.2/spec.html#Object+Container+Files
+// A new header in the stream replaces the current schema, so multiple containers can follow each other.
+type ContainerReader struct {
+	reader *bufio.Reader
+	schema *Schema
+	codec  string
+	sync   []byte
+	block  []byte
+	pos    int
+	count  int64
+}
+
+// NewContainerReader returns a reader for the given stream
+func NewContainerReader(r io.Reader) *ContainerReader {
+	return &ContainerReader{
+		reader: bufio.NewReader(r),
+	}
+}
+
+// Schema of the current container, nil until the first header is read
+func (c *ContainerReader) Schema() *Schema {
+	return c.schema
+}
+
+// Next returns the next record in Avro binary encoding and its decoded value.
+// Returns io.EOF if the stream ended.
+func (c *ContainerReader) Next() ([]byte, interface{}, error) {
+	for c.count == 0 {
+		if err := c.readBlock(); err != nil {
+			return nil, nil, err
+		}
+	}
+	reader := bytes.NewReader(c.block[c.pos:])
+	value, err := Decode(c.schema, reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "decode record failed")
+	}
+	end := len(c.block) - reader.Len()
+	data := c.block[c.pos:end]
+	c.pos = end
+	c.count--
+	return data, value, nil
+}
+
+func (c *ContainerReader) readBlock() error {
+	if c.schema == nil {
+		return c.readHeader()
+	}
+	prefix, err := c.reader.Peek(len(Magic))
+	if err == io.EOF && len(prefix) == 0 {
+		return io.EOF
+	}
+	if err != nil {
+		return errors.Wrap(err, "read block failed")
+	}
+	if bytes.Equal(prefix, Magic) {
+		return c.readHeader()
+	}
+	count, err := readLong(c.reader)
+	if err != nil {
+		return err
+	}
+	size, err := readLong(c.reader)
+	if err != nil {
+		return err
+	}
+	if count < 0 || size < 0 || size > maxLength {
+		return errors.Errorf("invalid block with count %d and size %d", count, size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(c.reader, data); err != nil {
+		return errors.Wrap(err, "read block data failed")
+	}
+	if err := c.readSync(); err != nil {
+		return err
+	}
+	if c.codec == "deflate" {
+		data, err = ioutil.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(data)), maxLength+1))
+		if err != nil {
+			return errors.Wrap(err, "inflate block failed")
+		}
+		if len(data) > maxLength {
+			return errors.Errorf("inflated block exceeds %d bytes", maxLength)
+		}
+	}
+	c.block = data
+	c.pos = 0
+	c.count = count
+	return nil
+}
+
+func (c *ContainerReader) readHeader() error {
+	magic := make([]byte, len(Magic))
+	if _, err := io.ReadFull(c.reader, magic); err != nil {
+		if err == io.EOF {
+			return io.EOF
+		}
+		return errors.Wrap(err, "read magic failed")
+	}
+	if !bytes.Equal(magic, Magic) {
+		return errors.Errorf("invalid magic %q", magic)
+	}
+	meta := make(map[string][]byte)
+	err := readBlocks(c.reader, func() error {
+		key, err := readBytes(c.reader)
+		if err != nil {
+			return err
+		}
+		value, err := readBytes(c.reader)
+		if err != nil {
+			return err
+		}
+		meta[string(key)] = value
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "read metadata failed")
+	}
+	schema, err := ParseSchema(meta["avro.schema"])
+	if err != nil {
+		return errors.Wrap(err, "parse schema failed")
+	}
+	codec := string(meta["avro.codec"])
+	switch codec {
+	case "", "null", "deflate":
+	default:
+		return errors.Errorf("unsupported codec %s", codec)
+	}
+	c.sync = make([]byte, syncLength)
+	if _, err := io.ReadFull(c.reader, c.sync); err != nil {
+		return errors.Wrap(err, "read sync marker failed")
+	}
+	c.schema = schema
+	c.codec = codec
+	c.block = nil
+	c.pos = 0
+	c.count = 0
+	return nil
+}
+
+func (c *ContainerReader) readSync() error {
+	sync := make([]byte, syncLength)
+	if _, err := io.ReadFull(c.reader, sync); err != nil {
+		return errors.Wrap(err, "read sync marker failed")
+	}
+	if !bytes.Equal(sync, c.sync) {
+		return errors.New("sync marker mismatch")
+	}
+	return nil
+}
+
+// ContainerWriter writes records as Avro object container without compression
+type ContainerWriter struct {
+	writer io.Writer
+	schema *Schema
+	sync   []byte
+}
+
+// NewContainerWriter returns a writer for the given schema
+func NewContainerWriter(w io.Writer, schema *Schema) *ContainerWriter {
+	return &ContainerWriter{
+		writer: w,
+		schema: schema,
+	}
+}
+
+// WriteBlock writes the given values as one block. The header is written before the first block.
+func (c *ContainerWriter) WriteBlock(values ...interface{}) error {
+	if c.sync == nil {
+		if err := c.writeHeader(); err != nil {
+			return err
+		}
+	}
+	buf := &bytes.Buffer{}
+	for _, value := range values {
+		if err := Encode(c.schema, buf, value); err != nil {
+			return errors.Wrap(err, "encode record failed")
+		}
+	}
+	if err := writeLong(c.writer, int64(len(values))); err != nil {
+		return err
+	}
+	if err := writeBytes(c.writer, buf.Bytes()); err != nil {
+		return err
+	}
+	return writeRaw(c.writer, c.sync)
+}
+
+func (c *ContainerWriter) writeHeader() error {
+	sync := make([]byte, syncLength)
+	if _, err := rand.Read(sync); err != nil {
+		return errors.Wrap(err, "create sync marker failed")
+	}
+	if err := writeRaw(c.writer, Magic); err != nil {
+		return err
+	}
+	meta := &Schema{Type: TypeMap, Values: &Schema{Type: TypeBytes}}
+	err := Encode(meta, c.writer, map[string]interface{}{
+		"avro.schema": []byte(c.schema.String()),
+		"avro.codec":  []byte("null"),
+	})
+	if err != nil {
+		return errors.Wrap(err, "write metadata failed")
+	}
+	if err := writeRaw(c.writer, sync); err != nil {
+		return err
+	}
+	c.sync = sync
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package avro_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ContainerReader", func() {
+	var schema *avro.Schema
+
+	record := func(sequence int, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"domain":       0,
+			"server_id":    1,
+			"sequence":     sequence,
+			"event_number": 1,
+			"timestamp":    1541348151,
+			"event_type":   "insert",
+			"id":           sequence,
+			"name":         name,
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		schema, err = avro.ParseSchema([]byte(changeRecordSchema))
+		Expect(err).To(BeNil())
+	})
+
+	It("reads all records of all blocks", func() {
+		buf := &bytes.Buffer{}
+		writer := avro.NewContainerWriter(buf, schema)
+		Expect(writer.WriteBlock(record(1, "a"), record(2, "b"))).To(BeNil())
+		Expect(writer.WriteBlock(record(3, "c"))).To(BeNil())
+
+		reader := avro.NewContainerReader(buf)
+		for i := 1; i <= 3; i++ {
+			data, value, err := reader.Next()
+			Expect(err).To(BeNil())
+			Expect(value).To(HaveKeyWithValue("sequence", int32(i)))
+			decoded, err := avro.Decode(reader.Schema(), bytes.NewReader(data))
+			Expect(err).To(BeNil())
+			Expect(decoded).To(Equal(value))
+		}
+		_, _, err := reader.Next()
+		Expect(err).To(Equal(io.EOF))
+		Expect(reader.Schema().String()).To(Equal(changeRecordSchema))
+	})
+
+	It("switches schema on new header", func() {
+		otherSchema, err := avro.ParseSchema([]byte(`{"type":"record","name":"other","fields":[{"name":"sequence","type":"long"}]}`))
+		Expect(err).To(BeNil())
+		buf := &bytes.Buffer{}
+		Expect(avro.NewContainerWriter(buf, schema).WriteBlock(record(1, "a"))).To(BeNil())
+		Expect(avro.NewContainerWriter(buf, otherSchema).WriteBlock(map[string]interface{}{"sequence": 2})).To(BeNil())
+
+		reader := avro.NewContainerReader(buf)
+		_, value, err := reader.Next()
+		Expect(err).To(BeNil())
+		Expect(value).To(HaveKeyWithValue("sequence", int32(1)))
+		Expect(reader.Schema().Name).To(Equal("ChangeRecord"))
+		_, value, err = reader.Next()
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal(map[string]interface{}{"sequence": int64(2)}))
+		Expect(reader.Schema().Name).To(Equal("other"))
+	})
+
+	It("returns error on invalid magic", func() {
+		reader := avro.NewContainerReader(bytes.NewBufferString("banana banana"))
+		_, _, err := reader.Next()
+		Expect(err).NotTo(BeNil())
+		Expect(err).NotTo(Equal(io.EOF))
+	})
+
+	It("returns error on sync marker mismatch", func() {
+		buf := &bytes.Buffer{}
+		Expect(avro.NewContainerWriter(buf, schema).WriteBlock(record(1, "a"))).To(BeNil())
+		data := buf.Bytes()
+		data[len(data)-1]++
+		reader := avro.NewContainerReader(bytes.NewReader(data))
+		_, _, err := reader.Next()
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error on oversized block", func() {
+		buf := &bytes.Buffer{}
+		Expect(avro.NewContainerWriter(buf, schema).WriteBlock()).To(BeNil())
+		block := make([]byte, 2*binary.MaxVarintLen64)
+		n := binary.PutVarint(block, 1)
+		n += binary.PutVarint(block[n:], 1<<40)
+		buf.Write(block[:n])
+		reader := avro.NewContainerReader(buf)
+		_, _, err := reader.Next()
+		Expect(err).NotTo(BeNil())
+		Expect(err).NotTo(Equal(io.EOF))
+	})
+
+	It("returns EOF on empty stream", func() {
+		reader := avro.NewContainerReader(&bytes.Buffer{})
+		_, _, err := reader.Next()
+		Expect(err).To(Equal(io.EOF))
+	})
+})
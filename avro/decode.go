@@ -0,0 +1,177 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package avro
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// maxLength of bytes, strings and container blocks read from a stream.
+// Length fields above are rejected instead of allocating the buffer.
+const maxLength = 64 * 1024 * 1024
+
+// Reader required to decode Avro binary data
+type Reader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// Decode reads one value of the given schema from the reader.
+// Records and maps are returned as map[string]interface{}, arrays as []interface{},
+// enums as string and unions as the value of the selected type.
+func Decode(schema *Schema, r Reader) (interface{}, error) {
+	switch schema.Type {
+	case TypeNull:
+		return nil, nil
+	case TypeBoolean:
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, errors.Wrap(err, "read boolean failed")
+		}
+		return b != 0, nil
+	case TypeInt:
+		v, err := readLong(r)
+		if err != nil {
+			return nil, err
+		}
+		return int32(v), nil
+	case TypeLong:
+		return readLong(r)
+	case TypeFloat:
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, errors.Wrap(err, "read float failed")
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(buf)), nil
+	case TypeDouble:
+		buf := make([]byte, 8)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, errors.Wrap(err, "read double failed")
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(buf)), nil
+	case TypeBytes:
+		return readBytes(r)
+	case TypeString:
+		b, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case TypeFixed:
+		buf := make([]byte, schema.Size)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, errors.Wrap(err, "read fixed failed")
+		}
+		return buf, nil
+	case TypeEnum:
+		index, err := readLong(r)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || int(index) >= len(schema.Symbols) {
+			return nil, errors.Errorf("enum index %d out of range", index)
+		}
+		return schema.Symbols[index], nil
+	case TypeUnion:
+		index, err := readLong(r)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || int(index) >= len(schema.Types) {
+			return nil, errors.Errorf("union index %d out of range", index)
+		}
+		return Decode(schema.Types[index], r)
+	case TypeArray:
+		var result []interface{}
+		err := readBlocks(r, func() error {
+			item, err := Decode(schema.Items, r)
+			if err != nil {
+				return err
+			}
+			result = append(result, item)
+			return nil
+		})
+		return result, err
+	case TypeMap:
+		result := make(map[string]interface{})
+		err := readBlocks(r, func() error {
+			key, err := readBytes(r)
+			if err != nil {
+				return err
+			}
+			value, err := Decode(schema.Values, r)
+			if err != nil {
+				return err
+			}
+			result[string(key)] = value
+			return nil
+		})
+		return result, err
+	case TypeRecord:
+		result := make(map[string]interface{}, len(schema.Fields))
+		for _, field := range schema.Fields {
+			value, err := Decode(field.Type, r)
+			if err != nil {
+				return nil, errors.Wrapf(err, "decode field %s failed", field.Name)
+			}
+			result[field.Name] = value
+		}
+		return result, nil
+	default:
+		return nil, errors.Errorf("unsupported type %s", schema.Type)
+	}
+}
+
+func readLong(r io.ByteReader) (int64, error) {
+	v, err := binary.ReadVarint(r)
+	if err != nil {
+		return 0, errors.Wrap(err, "read varint failed")
+	}
+	return v, nil
+}
+
+func readBytes(r Reader) ([]byte, error) {
+	length, err := readLong(r)
+	if err != nil {
+		return nil, err
+	}
+	if length < 0 || length > maxLength {
+		return nil, errors.Errorf("invalid length %d", length)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, errors.Wrap(err, "read bytes failed")
+	}
+	return buf, nil
+}
+
+// readBlocks of arrays and maps and call fn for each item
+func readBlocks(r Reader, fn func() error) error {
+	for {
+		count, err := readLong(r)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			count = -count
+			// skip size of block
+			if _, err := readLong(r); err != nil {
+				return err
+			}
+		}
+		for i := int64(0); i < count; i++ {
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+	}
+}
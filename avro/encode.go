@@ -0,0 +1,237 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Encode writes the value in Avro binary format of the given schema.
+// Values are converted if possible, e.g. json.Number to long.
+func Encode(schema *Schema, w io.Writer, value interface{}) error {
+	switch schema.Type {
+	case TypeNull:
+		if value != nil {
+			return errors.Errorf("expected null but got %T", value)
+		}
+		return nil
+	case TypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return errors.Errorf("expected boolean but got %T", value)
+		}
+		if b {
+			return writeRaw(w, []byte{1})
+		}
+		return writeRaw(w, []byte{0})
+	case TypeInt:
+		v, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return errors.Errorf("value %d out of int range", v)
+		}
+		return writeLong(w, v)
+	case TypeLong:
+		v, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		return writeLong(w, v)
+	case TypeFloat:
+		v, err := toFloat64(value)
+		if err != nil {
+			return err
+		}
+		buf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(buf, math.Float32bits(float32(v)))
+		return writeRaw(w, buf)
+	case TypeDouble:
+		v, err := toFloat64(value)
+		if err != nil {
+			return err
+		}
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+		return writeRaw(w, buf)
+	case TypeBytes, TypeString:
+		switch v := value.(type) {
+		case string:
+			return writeBytes(w, []byte(v))
+		case []byte:
+			return writeBytes(w, v)
+		case json.Number:
+			if schema.Type == TypeString {
+				return writeBytes(w, []byte(v))
+			}
+		}
+		return errors.Errorf("expected %s but got %T", schema.Type, value)
+	case TypeFixed:
+		v, ok := value.([]byte)
+		if !ok {
+			if s, isString := value.(string); isString {
+				v, ok = []byte(s), true
+			}
+		}
+		if !ok || len(v) != schema.Size {
+			return errors.Errorf("expected fixed of size %d", schema.Size)
+		}
+		return writeRaw(w, v)
+	case TypeEnum:
+		symbol, ok := value.(string)
+		if !ok {
+			return errors.Errorf("expected enum symbol but got %T", value)
+		}
+		for i, s := range schema.Symbols {
+			if s == symbol {
+				return writeLong(w, int64(i))
+			}
+		}
+		return errors.Errorf("unknown enum symbol %s", symbol)
+	case TypeUnion:
+		for i, t := range schema.Types {
+			if (value == nil) != (t.Type == TypeNull) {
+				continue
+			}
+			buf := &bytes.Buffer{}
+			if err := Encode(t, buf, value); err != nil {
+				continue
+			}
+			if err := writeLong(w, int64(i)); err != nil {
+				return err
+			}
+			return writeRaw(w, buf.Bytes())
+		}
+		return errors.Errorf("no type of union matches %T", value)
+	case TypeArray:
+		items, ok := value.([]interface{})
+		if !ok {
+			return errors.Errorf("expected array but got %T", value)
+		}
+		if len(items) > 0 {
+			if err := writeLong(w, int64(len(items))); err != nil {
+				return err
+			}
+			for _, item := range items {
+				if err := Encode(schema.Items, w, item); err != nil {
+					return err
+				}
+			}
+		}
+		return writeLong(w, 0)
+	case TypeMap:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return errors.Errorf("expected map but got %T", value)
+		}
+		if len(values) > 0 {
+			if err := writeLong(w, int64(len(values))); err != nil {
+				return err
+			}
+			for key, v := range values {
+				if err := writeBytes(w, []byte(key)); err != nil {
+					return err
+				}
+				if err := Encode(schema.Values, w, v); err != nil {
+					return err
+				}
+			}
+		}
+		return writeLong(w, 0)
+	case TypeRecord:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return errors.Errorf("expected record but got %T", value)
+		}
+		for _, field := range schema.Fields {
+			v, ok := values[field.Name]
+			if !ok {
+				v = field.Default
+			}
+			if err := Encode(field.Type, w, v); err != nil {
+				return errors.Wrapf(err, "encode field %s failed", field.Name)
+			}
+		}
+		return nil
+	default:
+		return errors.Errorf("unsupported type %s", schema.Type)
+	}
+}
+
+func writeRaw(w io.Writer, b []byte) error {
+	_, err := w.Write(b)
+	return errors.Wrap(err, "write failed")
+}
+
+func writeLong(w io.Writer, v int64) error {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return writeRaw(w, buf[:binary.PutVarint(buf, v)])
+}
+
+func writeBytes(w io.Writer, b []byte) error {
+	if err := writeLong(w, int64(len(b))); err != nil {
+		return err
+	}
+	return writeRaw(w, b)
+}
+
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, errors.Errorf("value %v is not an integer", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		return strconv.ParseInt(string(v), 10, 64)
+	default:
+		return 0, errors.Errorf("expected integer but got %T", value)
+	}
+}
+
+func toFloat64(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case json.Number:
+		return v.Float64()
+	default:
+		i, err := toInt64(value)
+		if err != nil {
+			return 0, errors.Errorf("expected number but got %T", value)
+		}
+		return float64(i), nil
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package avro
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Avro schema types
+const (
+	TypeNull    = "null"
+	TypeBoolean = "boolean"
+	TypeInt     = "int"
+	TypeLong    = "long"
+	TypeFloat   = "float"
+	TypeDouble  = "double"
+	TypeBytes   = "bytes"
+	TypeString  = "string"
+	TypeRecord  = "record"
+	TypeEnum    = "enum"
+	TypeArray   = "array"
+	TypeMap     = "map"
+	TypeFixed   = "fixed"
+	TypeUnion   = "union"
+)
+
+// Schema of Avro data
+// https://avro.apache.org/docs/1.8.2/spec.html#schemas
+type Schema struct {
+	Type      string
+	Name      string
+	Namespace string
+	Fields    []*Field
+	Symbols   []string
+	Items     *Schema
+	Values    *Schema
+	Size      int
+	Types     []*Schema
+	raw       []byte
+}
+
+// Field of a record schema
+type Field struct {
+	Name    string
+	Type    *Schema
+	Default interface{}
+	// Attributes contains all additional attributes of the field, e.g. real_type and length written by Maxscale
+	Attributes map[string]interface{}
+}
+
+// ParseSchema returns the schema for the given json
+func ParseSchema(data []byte) (*Schema, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, errors.Wrap(err, "decode schema json failed")
+	}
+	schema, err := parseSchema(value, "", make(map[string]*Schema))
+	if err != nil {
+		return nil, err
+	}
+	schema.raw = append([]byte{}, data...)
+	return schema, nil
+}
+
+// String returns the schema as json
+func (s *Schema) String() string {
+	if s == nil {
+		return ""
+	}
+	return string(s.raw)
+}
+
+// FullName returns the name including namespace of named schemas
+func (s *Schema) FullName() string {
+	if s.Namespace == "" || strings.Contains(s.Name, ".") {
+		return s.Name
+	}
+	return s.Namespace + "." + s.Name
+}
+
+// Field returns the field with the given name or nil
+func (s *Schema) Field(name string) *Field {
+	for _, field := range s.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// Nullable returns true if the schema is null or a union with null
+func (s *Schema) Nullable() bool {
+	if s.Type == TypeNull {
+		return true
+	}
+	for _, t := range s.Types {
+		if t.Type == TypeNull {
+			return true
+		}
+	}
+	return false
+}
+
+func parseSchema(value interface{}, namespace string, names map[string]*Schema) (*Schema, error) {
+	switch v := value.(type) {
+	case string:
+		return parseTypeName(v, namespace, names)
+	case []interface{}:
+		schema := &Schema{Type: TypeUnion}
+		for _, t := range v {
+			sub, err := parseSchema(t, namespace, names)
+			if err != nil {
+				return nil, err
+			}
+			schema.Types = append(schema.Types, sub)
+		}
+		return schema, nil
+	case map[string]interface{}:
+		return parseComplex(v, namespace, names)
+	default:
+		return nil, errors.Errorf("invalid schema %v", value)
+	}
+}
+
+func parseTypeName(name string, namespace string, names map[string]*Schema) (*Schema, error) {
+	switch name {
+	case TypeNull, TypeBoolean, TypeInt, TypeLong, TypeFloat, TypeDouble, TypeBytes, TypeString:
+		return &Schema{Type: name}, nil
+	}
+	if schema, ok := names[name]; ok {
+		return schema, nil
+	}
+	if schema, ok := names[namespace+"."+name]; ok {
+		return schema, nil
+	}
+	return nil, errors.Errorf("unknown type %s", name)
+}
+
+func parseComplex(value map[string]interface{}, namespace string, names map[string]*Schema) (*Schema, error) {
+	switch t := value["type"].(type) {
+	case []interface{}, map[string]interface{}:
+		return parseSchema(t, namespace, names)
+	case string:
+		schema := &Schema{Type: t}
+		switch t {
+		case TypeRecord, TypeEnum, TypeFixed:
+			schema.Name, _ = value["name"].(string)
+			if schema.Name == "" {
+				return nil, errors.Errorf("name of %s missing", t)
+			}
+			schema.Namespace, _ = value["namespace"].(string)
+			if schema.Namespace == "" {
+				schema.Namespace = namespace
+			}
+			names[schema.FullName()] = schema
+			names[schema.Name] = schema
+		}
+		switch t {
+		case TypeRecord:
+			fields, _ := value["fields"].([]interface{})
+			for _, f := range fields {
+				field, err := parseField(f, schema.Namespace, names)
+				if err != nil {
+					return nil, err
+				}
+				schema.Fields = append(schema.Fields, field)
+			}
+		case TypeEnum:
+			symbols, _ := value["symbols"].([]interface{})
+			for _, symbol := range symbols {
+				s, ok := symbol.(string)
+				if !ok {
+					return nil, errors.Errorf("invalid symbol %v", symbol)
+				}
+				schema.Symbols = append(schema.Symbols, s)
+			}
+		case TypeFixed:
+			size, ok := value["size"].(float64)
+			if !ok {
+				return nil, errors.New("size of fixed missing")
+			}
+			schema.Size = int(size)
+		case TypeArray:
+			items, err := parseSchema(value["items"], namespace, names)
+			if err != nil {
+				return nil, errors.Wrap(err, "parse items failed")
+			}
+			schema.Items = items
+		case TypeMap:
+			values, err := parseSchema(value["values"], namespace, names)
+			if err != nil {
+				return nil, errors.Wrap(err, "parse values failed")
+			}
+			schema.Values = values
+		default:
+			return parseTypeName(t, namespace, names)
+		}
+		return schema, nil
+	default:
+		return nil, errors.Errorf("invalid type %v", value["type"])
+	}
+}
+
+func parseField(value interface{}, namespace string, names map[string]*Schema) (*Field, error) {
+	v, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("invalid field %v", value)
+	}
+	field := &Field{
+		Attributes: make(map[string]interface{}),
+	}
+	for key, attribute := range v {
+		switch key {
+		case "name":
+			field.Name, _ = attribute.(string)
+		case "type":
+			schema, err := parseSchema(attribute, namespace, names)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse type of field %v failed", v["name"])
+			}
+			field.Type = schema
+		case "default":
+			field.Default = attribute
+		default:
+			field.Attributes[key] = attribute
+		}
+	}
+	if field.Name == "" {
+		return nil, errors.New("name of field missing")
+	}
+	if field.Type == nil {
+		return nil, errors.Errorf("type of field %s missing", field.Name)
+	}
+	return field, nil
+}
+
+// MarshalJSON returns the original json of parsed schemas, otherwise the json is built from the schema
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	if len(s.raw) > 0 {
+		return s.raw, nil
+	}
+	return json.Marshal(s.toJSON(make(map[string]bool)))
+}
+
+func (s *Schema) toJSON(defined map[string]bool) interface{} {
+	switch s.Type {
+	case TypeUnion:
+		var types []interface{}
+		for _, t := range s.Types {
+			types = append(types, t.toJSON(defined))
+		}
+		return types
+	case TypeRecord, TypeEnum, TypeFixed:
+		if defined[s.FullName()] {
+			return s.FullName()
+		}
+		defined[s.FullName()] = true
+	}
+	result := map[string]interface{}{
+		"type": s.Type,
+	}
+	if s.Name != "" {
+		result["name"] = s.Name
+	}
+	if s.Namespace != "" {
+		result["namespace"] = s.Namespace
+	}
+	switch s.Type {
+	case TypeRecord:
+		fields := []interface{}{}
+		for _, field := range s.Fields {
+			f := map[string]interface{}{}
+			for key, value := range field.Attributes {
+				f[key] = value
+			}
+			f["name"] = field.Name
+			f["type"] = field.Type.toJSON(defined)
+			if field.Default != nil {
+				f["default"] = field.Default
+			}
+			fields = append(fields, f)
+		}
+		result["fields"] = fields
+	case TypeEnum:
+		result["symbols"] = s.Symbols
+	case TypeFixed:
+		result["size"] = s.Size
+	case TypeArray:
+		result["items"] = s.Items.toJSON(defined)
+	case TypeMap:
+		result["values"] = s.Values.toJSON(defined)
+	}
+	return result
+}
+
+// NewRecordSchema returns a record schema with the given fields
+func NewRecordSchema(namespace string, name string, fields []*Field) (*Schema, error) {
+	data, err := json.Marshal(&Schema{
+		Type:      TypeRecord,
+		Namespace: namespace,
+		Name:      name,
+		Fields:    fields,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "encode schema failed")
+	}
+	return ParseSchema(data)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package avro_test
+
+import (
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const changeRecordSchema = `{
+	"namespace": "MaxScaleChangeDataSchema.avro",
+	"type": "record",
+	"name": "ChangeRecord",
+	"fields": [
+		{"name": "domain", "type": "int"},
+		{"name": "server_id", "type": "int"},
+		{"name": "sequence", "type": "int"},
+		{"name": "event_number", "type": "int"},
+		{"name": "timestamp", "type": "int"},
+		{"name": "event_type", "type": {"type": "enum", "name": "EVENT_TYPES", "symbols": ["insert", "update_before", "update_after", "delete"]}},
+		{"name": "id", "type": {"type": "int"}, "real_type": "int", "length": -1},
+		{"name": "name", "type": ["null", "string"], "real_type": "varchar", "length": 20}
+	]
+}`
+
+var _ = Describe("Schema", func() {
+
+	It("parses record schema", func() {
+		schema, err := avro.ParseSchema([]byte(changeRecordSchema))
+		Expect(err).To(BeNil())
+		Expect(schema.Type).To(Equal(avro.TypeRecord))
+		Expect(schema.FullName()).To(Equal("MaxScaleChangeDataSchema.avro.ChangeRecord"))
+		Expect(schema.Fields).To(HaveLen(8))
+		Expect(schema.Field("event_type").Type.Symbols).To(Equal([]string{"insert", "update_before", "update_after", "delete"}))
+		Expect(schema.Field("id").Type.Type).To(Equal(avro.TypeInt))
+		Expect(schema.Field("id").Attributes).To(HaveKeyWithValue("real_type", "int"))
+		Expect(schema.Field("name").Type.Type).To(Equal(avro.TypeUnion))
+		Expect(schema.Field("name").Type.Nullable()).To(BeTrue())
+		Expect(schema.Field("banana")).To(BeNil())
+	})
+
+	It("returns the original json as string", func() {
+		schema, err := avro.ParseSchema([]byte(changeRecordSchema))
+		Expect(err).To(BeNil())
+		Expect(schema.String()).To(Equal(changeRecordSchema))
+	})
+
+	It("resolves named types", func() {
+		schema, err := avro.ParseSchema([]byte(`{"type":"record","name":"r","fields":[
+			{"name":"a","type":{"type":"fixed","name":"md5","size":16}},
+			{"name":"b","type":"md5"}
+		]}`))
+		Expect(err).To(BeNil())
+		Expect(schema.Field("b").Type.Type).To(Equal(avro.TypeFixed))
+		Expect(schema.Field("b").Type.Size).To(Equal(16))
+	})
+
+	It("returns error for unknown type", func() {
+		_, err := avro.ParseSchema([]byte(`{"type":"record","name":"r","fields":[{"name":"a","type":"banana"}]}`))
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error for invalid json", func() {
+		_, err := avro.ParseSchema([]byte(`banana`))
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("NewRecordSchema", func() {
+
+	It("builds schema of the given fields", func() {
+		source, err := avro.ParseSchema([]byte(changeRecordSchema))
+		Expect(err).To(BeNil())
+		schema, err := avro.NewRecordSchema("mydb", "mytable_key", []*avro.Field{
+			source.Field("id"),
+			source.Field("name"),
+			source.Field("event_type"),
+		})
+		Expect(err).To(BeNil())
+		Expect(schema.FullName()).To(Equal("mydb.mytable_key"))
+		Expect(schema.Fields).To(HaveLen(3))
+		Expect(schema.Field("id").Type.Type).To(Equal(avro.TypeInt))
+		Expect(schema.Field("id").Attributes).To(HaveKeyWithValue("real_type", "int"))
+		Expect(schema.Field("name").Type.Nullable()).To(BeTrue())
+		Expect(schema.Field("event_type").Type.Symbols).To(HaveLen(4))
+		Expect(schema.String()).To(ContainSubstring(`"name":"mytable_key"`))
+	})
+})
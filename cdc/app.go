@@ -8,7 +8,13 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/Shopify/sarama"
 	"github.com/bborbe/run"
 	"github.com/golang/glog"
 	"github.com/gorilla/mux"
@@ -24,13 +30,85 @@ type App struct {
 	CdcPassword  string
 	CdcPort      int
 	CdcTable     string
+	CdcTables    string
 	CdcUser      string
 	CdcUUID      string
 	CdcGTID      string
 	KafkaBrokers string
 	KafkaTopic   string
-	Port         int
-	DataDir      string
+	KafkaFormat  string
+	// KafkaTopicOverrides is a comma separated list of DATABASE.TABLE=TOPIC
+	KafkaTopicOverrides string
+	// KafkaTopicColumns is a comma separated list of columns that topics can use as placeholders
+	KafkaTopicColumns string
+	// KafkaTopicMappings replace values of topic placeholders, e.g. tenant_id=42:acme+7:globex
+	KafkaTopicMappings string
+	// KafkaTopicDefault is the topic of records with missing, null or unmapped placeholder values
+	KafkaTopicDefault string
+	Port              int
+	DataDir           string
+
+	CdcExcludeTables     string
+	CdcAvroDir           string
+	CdcDiscoveryInterval time.Duration
+
+	SchemaRegistryURL                 string
+	SchemaRegistrySubjectNameStrategy string
+
+	// KafkaKey is the format of the message key, default is the GTID
+	KafkaKey string
+	// KafkaKeyColumns is a comma separated list of DATABASE.TABLE=COLUMN+COLUMN
+	KafkaKeyColumns string
+	// KafkaTombstones is a comma separated list of tables or patterns that send tombstones for deletes
+	KafkaTombstones string
+	// KafkaTransactions sends the events of a GTID as one batch, optionally followed by an end marker
+	KafkaTransactions       bool
+	KafkaTransactionTimeout time.Duration
+	KafkaTransactionEnd     bool
+	// KafkaAsync sends with an async producer, batched and compressed by the given options
+	KafkaAsync       bool
+	KafkaBatchSize   int
+	KafkaLinger      time.Duration
+	KafkaMaxInFlight int
+	KafkaCompression string
+	// KafkaEnvelope wraps the record in the envelope of other change data capture tools
+	KafkaEnvelope string
+	// KafkaCloudEventsMode is structured or binary, the content mode of the CloudEvents envelope
+	KafkaCloudEventsMode string
+	// KafkaHeaders adds database, table, version, event type, GTID, event number, UUID and host as headers
+	KafkaHeaders bool
+
+	// DropEventTypes, IncludeColumns and ExcludeColumns filter records by table pattern, e.g. shop.*=update_before+delete
+	DropEventTypes string
+	IncludeColumns string
+	ExcludeColumns string
+	// RowFilters forwards only rows matching the expression of the table pattern, separated by semicolon
+	RowFilters string
+	// MaskColumns masks columns by table pattern, e.g. shop.customers=email:hash+phone:truncate:4
+	MaskColumns string
+	// MaskKey is the secret of hash and tokenize
+	MaskKey string
+	// KafkaSchemaTopic is the topic new table schema versions are published to, keyed by DATABASE.TABLE.VERSION
+	KafkaSchemaTopic string
+
+	// OutboxTables is a comma separated list of tables or patterns of transactional outbox tables
+	OutboxTables string
+	// OutboxTopic is the topic template of outbox events, by default routed by aggregate type
+	OutboxTopic string
+	// OutboxTombstones sends a tombstone for the aggregate id of deleted outbox rows instead of ignoring them
+	OutboxTombstones bool
+
+	// GTIDRecovery defines if the start GTID is read from the last messages in Kafka
+	GTIDRecovery string
+
+	// CheckpointStore is the backend that stores the last GTID of each table
+	CheckpointStore string
+	// CheckpointTopic is the compacted topic of the kafka checkpoint store
+	CheckpointTopic string
+	// Flush policy of the checkpoint store, without the GTID of every record is written
+	CheckpointFlushEvents      int
+	CheckpointFlushInterval    time.Duration
+	CheckpointFlushTransaction bool
 }
 
 // Validate returns an error if not all required parameter are set
@@ -38,15 +116,64 @@ func (a *App) Validate() error {
 	if a.Port <= 0 {
 		return errors.New("Port missing")
 	}
-	if a.DataDir == "" {
+	if a.CheckpointStore != "" && !ValidCheckpointStore(a.CheckpointStore) {
+		return errors.New("CheckpointStore invalid")
+	}
+	if a.checkpointStoreFile() && a.DataDir == "" {
 		return errors.New("DataDir missing")
 	}
+	if a.CheckpointStore == CheckpointStoreKafka && a.CheckpointTopic == "" {
+		return errors.New("CheckpointTopic missing")
+	}
+	// checkpoints in kafka are keyed by the uuid, a random uuid finds no checkpoint after a restart
+	if a.CheckpointStore == CheckpointStoreKafka && a.CdcUUID == "" {
+		return errors.New("CdcUUID missing, required by the kafka checkpoint store")
+	}
+	if a.CheckpointFlushEvents < 0 {
+		return errors.New("CheckpointFlushEvents invalid")
+	}
+	if a.CheckpointFlushInterval < 0 {
+		return errors.New("CheckpointFlushInterval invalid")
+	}
 	if a.KafkaBrokers == "" {
 		return errors.New("KafkaBrokers missing")
 	}
 	if a.KafkaTopic == "" {
 		return errors.New("KafkaTopic missing")
 	}
+	topicColumns, err := ParseTopicColumns(a.KafkaTopicColumns)
+	if err != nil {
+		return errors.Wrap(err, "KafkaTopicColumns invalid")
+	}
+	if err := ValidateTopicTemplate(a.KafkaTopic, topicColumns...); err != nil {
+		return errors.Wrap(err, "KafkaTopic invalid")
+	}
+	if _, err := ParseTopicOverrides(a.KafkaTopicOverrides, topicColumns...); err != nil {
+		return errors.Wrap(err, "KafkaTopicOverrides invalid")
+	}
+	if err := a.outboxMatcher().Validate(); err != nil {
+		return errors.Wrap(err, "OutboxTables invalid")
+	}
+	if err := ValidateTopicTemplate(a.outboxTopic(), append(topicColumns, OutboxAggregateType)...); err != nil {
+		return errors.Wrap(err, "OutboxTopic invalid")
+	}
+	if a.OutboxTables != "" && a.gtidRecovery() {
+		return errors.New("GTIDRecovery does not work with OutboxTables, outbox messages contain no GTID")
+	}
+	if _, err := ParseTopicMappings(a.KafkaTopicMappings, topicColumns...); err != nil {
+		return errors.Wrap(err, "KafkaTopicMappings invalid")
+	}
+	if a.KafkaTopicDefault != "" {
+		if err := ValidateTopicTemplate(a.KafkaTopicDefault); err != nil {
+			return errors.Wrap(err, "KafkaTopicDefault invalid")
+		}
+		if _, err := (&TopicTemplate{Template: a.KafkaTopicDefault}).TableTopic(Table{Database: "database", Name: "table"}); err != nil {
+			return errors.Wrap(err, "KafkaTopicDefault may only contain {database} and {table}")
+		}
+	}
+	if _, err := a.recordFilters(); err != nil {
+		return err
+	}
 	if a.CdcHost == "" {
 		return errors.New("CdcHost missing")
 	}
@@ -59,24 +186,223 @@ func (a *App) Validate() error {
 	if a.CdcPassword == "" {
 		return errors.New("CdcPassword missing")
 	}
-	if a.CdcDatabase == "" {
+	if a.CdcDatabase == "" && a.CdcTable != "" {
 		return errors.New("CdcDatabase missing")
 	}
-	if a.CdcTable == "" {
+	tables, patterns, err := a.tables()
+	if err != nil {
+		return errors.Wrap(err, "CdcTables invalid")
+	}
+	if len(tables) == 0 && len(patterns) == 0 {
 		return errors.New("CdcTable missing")
 	}
+	if len(patterns) > 0 && a.CdcAvroDir == "" {
+		return errors.New("CdcAvroDir missing")
+	}
+	if err := a.tableMatcher(patterns).Validate(); err != nil {
+		return errors.Wrap(err, "CdcTables invalid")
+	}
+	if a.CdcAvroDir != "" && a.CdcDiscoveryInterval <= 0 {
+		return errors.New("CdcDiscoveryInterval missing")
+	}
 	if a.CdcUUID == "" {
 		return errors.New("CdcUUID missing")
 	}
-	if a.CdcTable == "" {
-		return errors.New("CdcTable missing")
+	if _, err := ParseGTIDPosition(a.CdcGTID); err != nil {
+		return errors.Wrap(err, "CdcGTID invalid")
 	}
 	if a.CdcFormat != "JSON" && a.CdcFormat != "AVRO" {
 		return errors.New("CdcFormat invalid")
 	}
+	if a.KafkaFormat != "" && a.KafkaFormat != "JSON" && a.KafkaFormat != "AVRO" {
+		return errors.New("KafkaFormat invalid")
+	}
+	if a.SchemaRegistryURL != "" && a.kafkaFormat() != "AVRO" {
+		return errors.New("SchemaRegistryURL requires KafkaFormat AVRO")
+	}
+	if a.SchemaRegistryURL != "" && !ValidSubjectNameStrategy(a.SchemaRegistrySubjectNameStrategy) {
+		return errors.New("SchemaRegistrySubjectNameStrategy invalid")
+	}
+	if a.KafkaKey != "" && !ValidKeyFormat(a.KafkaKey) {
+		return errors.New("KafkaKey invalid")
+	}
+	if _, err := ParseKeyColumns(a.KafkaKeyColumns); err != nil {
+		return errors.Wrap(err, "KafkaKeyColumns invalid")
+	}
+	if a.KafkaKeyColumns != "" && !a.primaryKey() {
+		return errors.New("KafkaKeyColumns requires KafkaKey string, json or avro")
+	}
+	if err := a.validateKeyColumns(); err != nil {
+		return err
+	}
+	if err := a.tombstoneMatcher().Validate(); err != nil {
+		return errors.Wrap(err, "KafkaTombstones invalid")
+	}
+	if a.KafkaTombstones != "" && !a.primaryKey() {
+		return errors.New("KafkaTombstones requires KafkaKey string, json or avro")
+	}
+	if a.KafkaTransactionTimeout < 0 {
+		return errors.New("KafkaTransactionTimeout invalid")
+	}
+	if a.KafkaTransactionEnd && !a.KafkaTransactions {
+		return errors.New("KafkaTransactionEnd requires KafkaTransactions")
+	}
+	if a.KafkaTransactionEnd && a.kafkaFormat() != "JSON" {
+		return errors.New("KafkaTransactionEnd requires KafkaFormat JSON")
+	}
+	if a.KafkaEnvelope != "" && !ValidEnvelope(a.KafkaEnvelope) {
+		return errors.New("KafkaEnvelope invalid")
+	}
+	if a.KafkaEnvelope == EnvelopeCloudEvents && !ValidCloudEventsMode(a.cloudEventsMode()) {
+		return errors.New("KafkaCloudEventsMode invalid")
+	}
+	if a.envelope() && a.kafkaFormat() != "JSON" && !a.binaryCloudEvents() {
+		return errors.New("KafkaEnvelope requires KafkaFormat JSON")
+	}
+	if a.KafkaBatchSize < 0 || a.KafkaLinger < 0 || a.KafkaMaxInFlight < 0 {
+		return errors.New("KafkaBatchSize, KafkaLinger and KafkaMaxInFlight must not be negative")
+	}
+	if a.KafkaBatchSize > 0 && a.KafkaLinger == 0 {
+		return errors.New("KafkaBatchSize requires KafkaLinger, a partial batch is never flushed")
+	}
+	if _, err := ParseCompression(a.KafkaCompression); err != nil {
+		return errors.Wrap(err, "KafkaCompression invalid")
+	}
+	if err := a.validateGTIDRecovery(); err != nil {
+		return errors.Wrap(err, "GTIDRecovery invalid")
+	}
 	return nil
 }
 
+// checkpointStoreFile returns true if GTIDs are stored in the DataDir
+func (a *App) checkpointStoreFile() bool {
+	return a.CheckpointStore == "" || a.CheckpointStore == CheckpointStoreFile
+}
+
+// gtidRecovery returns true if the start GTID is read from Kafka
+func (a *App) gtidRecovery() bool {
+	return a.GTIDRecovery != "" && a.GTIDRecovery != GTIDRecoveryNone
+}
+
+func (a *App) validateGTIDRecovery() error {
+	if !a.gtidRecovery() {
+		return nil
+	}
+	if !ValidGTIDRecovery(a.GTIDRecovery) {
+		return errors.Errorf("unknown mode %s", a.GTIDRecovery)
+	}
+	if a.primaryKey() && a.kafkaFormat() != "JSON" {
+		return errors.New("requires KafkaKey gtid or KafkaFormat JSON")
+	}
+	topicColumns, err := ParseTopicColumns(a.KafkaTopicColumns)
+	if err != nil {
+		return err
+	}
+	topicOverrides, err := ParseTopicOverrides(a.KafkaTopicOverrides, topicColumns...)
+	if err != nil {
+		return err
+	}
+	topicTemplate := &TopicTemplate{
+		Template:  a.KafkaTopic,
+		Overrides: topicOverrides,
+	}
+	tables := []Table{{Database: "database", Name: "table"}}
+	for table := range topicOverrides {
+		tables = append(tables, table)
+	}
+	for _, table := range tables {
+		if _, err := topicTemplate.TableTopic(table); err != nil {
+			return err
+		}
+	}
+	return a.validateTopicPerTable(topicTemplate)
+}
+
+// validateTopicPerTable returns an error if streamed tables can share a topic.
+// The recovered GTID is the highest of the topic, so a shared topic could skip events of a lagging table.
+func (a *App) validateTopicPerTable(topicTemplate *TopicTemplate) error {
+	tables, patterns, err := a.tables()
+	if err != nil {
+		return err
+	}
+	if len(patterns) > 0 && !(strings.Contains(a.KafkaTopic, "{database}") && strings.Contains(a.KafkaTopic, "{table}")) {
+		return errors.Errorf("discovered tables %s require {database} and {table} in KafkaTopic", strings.Join(patterns, ","))
+	}
+	owners := make(map[string]Table)
+	for _, table := range tables {
+		topic, err := topicTemplate.TableTopic(table)
+		if err != nil {
+			return err
+		}
+		if owner, ok := owners[topic]; ok {
+			return errors.Errorf("tables %s and %s share topic %s", owner, table, topic)
+		}
+		owners[topic] = table
+	}
+	return nil
+}
+
+// validateKeyColumns returns an error if a table has no primary key columns configured
+func (a *App) validateKeyColumns() error {
+	keyColumns, err := ParseKeyColumns(a.KafkaKeyColumns)
+	if err != nil {
+		return errors.Wrap(err, "KafkaKeyColumns invalid")
+	}
+	tables, _, err := a.tables()
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if err := a.validateTableKeyColumns(table, keyColumns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTableKeyColumns returns an error if the primary key of the table is used but not configured.
+// Outbox tables are keyed by aggregate id.
+func (a *App) validateTableKeyColumns(table Table, keyColumns map[Table][]string) error {
+	if !a.primaryKey() || a.outboxMatcher().Match(table) {
+		return nil
+	}
+	if _, ok := keyColumns[table]; !ok {
+		return errors.Errorf("KafkaKeyColumns of %s missing", table)
+	}
+	return nil
+}
+
+// primaryKey returns true if messages are keyed by the primary key columns
+func (a *App) primaryKey() bool {
+	return a.KafkaKey != "" && a.KafkaKey != KeyFormatGTID
+}
+
+// envelope returns true if records are wrapped in an envelope
+func (a *App) envelope() bool {
+	return a.KafkaEnvelope != "" && a.KafkaEnvelope != EnvelopeNone
+}
+
+func (a *App) cloudEventsMode() string {
+	if a.KafkaCloudEventsMode == "" {
+		return CloudEventsStructured
+	}
+	return a.KafkaCloudEventsMode
+}
+
+// binaryCloudEvents returns true if the record is the value and the CloudEvents attributes are headers,
+// this works for every format
+func (a *App) binaryCloudEvents() bool {
+	return a.KafkaEnvelope == EnvelopeCloudEvents && a.cloudEventsMode() == CloudEventsBinary
+}
+
+// kafkaFormat returns the format written to Kafka, default is the format read from Maxscale
+func (a *App) kafkaFormat() string {
+	if a.KafkaFormat == "" {
+		return a.CdcFormat
+	}
+	return a.KafkaFormat
+}
+
 // Run the app and blocks until error occurred or the context is canceled
 func (a *App) Run(ctx context.Context) error {
 	return run.CancelOnFirstFinish(
@@ -86,47 +412,592 @@ func (a *App) Run(ctx context.Context) error {
 	)
 }
 
+// tables returns all tables and table patterns of CdcDatabase, CdcTable and CdcTables.
+// CdcDatabase without CdcTable selects the whole database.
+func (a *App) tables() ([]Table, []string, error) {
+	list := a.CdcTables
+	if a.CdcDatabase != "" {
+		table := a.CdcTable
+		if table == "" {
+			table = "*"
+		}
+		list = fmt.Sprintf("%s.%s,%s", a.CdcDatabase, table, list)
+	}
+	all, err := ParseTables(list)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tables []Table
+	var patterns []string
+	for _, table := range all {
+		if IsTablePattern(table.String()) {
+			patterns = append(patterns, table.String())
+		} else {
+			tables = append(tables, table)
+		}
+	}
+	return tables, patterns, nil
+}
+
+func (a *App) tableMatcher(patterns []string) *TableMatcher {
+	return &TableMatcher{
+		Include: patterns,
+		Exclude: splitPatterns(a.CdcExcludeTables),
+	}
+}
+
+// tombstoneMatcher selects the tables that send tombstones for deletes
+func (a *App) tombstoneMatcher() *TableMatcher {
+	return &TableMatcher{
+		Include: splitPatterns(a.KafkaTombstones),
+	}
+}
+
+// outboxMatcher selects the transactional outbox tables
+func (a *App) outboxMatcher() *TableMatcher {
+	return &TableMatcher{
+		Include: splitPatterns(a.OutboxTables),
+	}
+}
+
+func (a *App) outboxTopic() string {
+	if a.OutboxTopic == "" {
+		return DefaultOutboxTopic
+	}
+	return a.OutboxTopic
+}
+
+// outbox sends the payload of inserts into the outbox table keyed by aggregate id to the topic of the aggregate type.
+// Topic overrides do not apply, the outbox topic routes all outbox tables. Mappings and default are kept.
+func (a *App) outbox(sender *KafkaSender, topicRouter *TopicTemplate) {
+	sender.TopicRouter = &TopicTemplate{
+		Template: a.outboxTopic(),
+		Mappings: topicRouter.Mappings,
+		Default:  topicRouter.Default,
+	}
+	sender.ValueEncoder = &OutboxEncoder{}
+	sender.KeyEncoder = &OutboxKeyEncoder{}
+	sender.HeaderEncoders = nil
+	if a.KafkaHeaders {
+		sender.HeaderEncoders = append(sender.HeaderEncoders, &MetadataHeaders{
+			UUID: a.CdcUUID,
+			Host: a.CdcHost,
+		})
+	}
+	sender.HeaderEncoders = append(sender.HeaderEncoders, &OutboxEncoder{})
+	// deletes are sent as tombstones by the encoder
+	sender.Tombstones = false
+	// consumers of outbox topics expect events only
+	sender.TransactionEnd = false
+}
+
+// outboxFilter drops updates and deletes of outbox tables, deletes are kept for tombstones
+func (a *App) outboxFilter() *EventTypeFilter {
+	drop := []string{"update_before", "update_after"}
+	if !a.OutboxTombstones {
+		drop = append(drop, "delete")
+	}
+	return &EventTypeFilter{
+		Drop: drop,
+	}
+}
+
+func splitPatterns(patterns string) []string {
+	var result []string
+	for _, pattern := range strings.Split(patterns, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			result = append(result, pattern)
+		}
+	}
+	return result
+}
+
 func (a *App) runStreamer(ctx context.Context) error {
-	gtid, err := ParseGTID(a.CdcGTID)
+	tables, patterns, err := a.tables()
+	if err != nil {
+		return errors.Wrap(err, "parse tables failed")
+	}
+	position, err := ParseGTIDPosition(a.CdcGTID)
 	if err != nil {
 		return errors.Wrap(err, "parse gtid failed")
 	}
-	gtidStore := &GTIDStore{
-		DataDir: a.DataDir,
+	var checkpoint *Checkpoint
+	if len(position) > 0 {
+		checkpoint = &Checkpoint{Position: position}
+	}
+	producer, err := NewSyncProducer(a.KafkaBrokers)
+	if err != nil {
+		return errors.Wrap(err, "create producer failed")
+	}
+	defer producer.Close()
+
+	topicColumns, err := ParseTopicColumns(a.KafkaTopicColumns)
+	if err != nil {
+		return errors.Wrap(err, "parse topic columns failed")
+	}
+	topicOverrides, err := ParseTopicOverrides(a.KafkaTopicOverrides, topicColumns...)
+	if err != nil {
+		return errors.Wrap(err, "parse topic overrides failed")
+	}
+	topicMappings, err := ParseTopicMappings(a.KafkaTopicMappings, topicColumns...)
+	if err != nil {
+		return errors.Wrap(err, "parse topic mappings failed")
+	}
+	filters, err := a.recordFilters()
+	if err != nil {
+		return err
+	}
+	deps := &dependencies{
+		producer: producer,
+		filters:  filters,
+		topicRouter: &TopicTemplate{
+			Template:  a.KafkaTopic,
+			Overrides: topicOverrides,
+			Mappings:  topicMappings,
+			Default:   a.KafkaTopicDefault,
+		},
+		schemaHistory: &SchemaHistory{
+			Producer: producer,
+			Topic:    a.KafkaSchemaTopic,
+		},
+	}
+	if a.gtidRecovery() || a.CheckpointStore == CheckpointStoreKafka || a.KafkaSchemaTopic != "" {
+		client, err := NewClient(a.KafkaBrokers)
+		if err != nil {
+			return errors.Wrap(err, "create client failed")
+		}
+		defer client.Close()
+		deps.client = client
+		deps.gtidReader = &KafkaGTIDReader{
+			Client: client,
+		}
+	}
+	if a.KafkaSchemaTopic != "" {
+		if err := deps.schemaHistory.Load(deps.client, 30*time.Second); err != nil {
+			return errors.Wrap(err, "load schema history failed")
+		}
 	}
-	if gtid == nil {
-		gtid, err = gtidStore.Read()
+	if a.KafkaAsync {
+		asyncClient, err := NewAsyncClient(a.KafkaBrokers, AsyncProducerConfig{
+			BatchSize:   a.KafkaBatchSize,
+			Linger:      a.KafkaLinger,
+			MaxInFlight: a.KafkaMaxInFlight,
+			Compression: a.KafkaCompression,
+		})
 		if err != nil {
-			glog.V(1).Infof("read gtid from disk failed")
+			return errors.Wrap(err, "create async client failed")
+		}
+		defer asyncClient.Close()
+		deps.asyncClient = asyncClient
+	}
+	var confluentEncoder *ConfluentEncoder
+	if a.SchemaRegistryURL != "" {
+		confluentEncoder = &ConfluentEncoder{
+			SchemaRegistry: &SchemaRegistryClient{
+				URL: a.SchemaRegistryURL,
+			},
+			SubjectNameStrategy: a.SchemaRegistrySubjectNameStrategy,
+		}
+		deps.valueEncoder = confluentEncoder
+	}
+	if a.KafkaHeaders {
+		deps.headerEncoders = append(deps.headerEncoders, &MetadataHeaders{
+			UUID: a.CdcUUID,
+			Host: a.CdcHost,
+		})
+	}
+	switch a.KafkaEnvelope {
+	case EnvelopeDebezium:
+		deps.valueEncoder = &DebeziumEncoder{}
+	case EnvelopeCloudEvents:
+		cloudEvents := &CloudEventsEncoder{
+			Mode:         a.cloudEventsMode(),
+			Host:         a.CdcHost,
+			ValueEncoder: deps.valueEncoder,
+		}
+		deps.valueEncoder = cloudEvents
+		deps.headerEncoders = append(deps.headerEncoders, cloudEvents)
+	}
+	if a.primaryKey() {
+		keyColumns, err := ParseKeyColumns(a.KafkaKeyColumns)
+		if err != nil {
+			return errors.Wrap(err, "parse key columns failed")
+		}
+		deps.keyColumns = keyColumns
+		deps.keyEncoder = &PrimaryKeyEncoder{
+			Format:    a.KafkaKey,
+			Columns:   keyColumns,
+			Confluent: confluentEncoder,
+		}
+	}
+
+	discovery := &TableDiscovery{
+		Tables:   tables,
+		Matcher:  a.tableMatcher(patterns),
+		Interval: a.CdcDiscoveryInterval,
+		Runner: func(table Table) run.RunFunc {
+			return a.tableRunner(table, checkpoint, deps)
+		},
+	}
+	if a.CdcAvroDir != "" {
+		discovery.Lister = &AvroDirTableLister{
+			Dir: a.CdcAvroDir,
+		}
+	}
+	return discovery.Run(ctx)
+}
+
+// dependencies shared by the streamers of all tables
+type dependencies struct {
+	producer       SyncProducer
+	topicRouter    *TopicTemplate
+	valueEncoder   ValueEncoder
+	headerEncoders []HeaderEncoder
+	keyEncoder     KeyEncoder
+	keyColumns     map[Table][]string
+	client         sarama.Client
+	gtidReader     *KafkaGTIDReader
+	asyncClient    sarama.Client
+	schemaHistory  *SchemaHistory
+	filters        *recordFilters
+}
+
+// recordFilters are the event types and columns dropped or masked by table pattern
+type recordFilters struct {
+	dropEventTypes TableLists
+	includeColumns TableLists
+	excludeColumns TableLists
+	rowFilters     map[string]*Expression
+	maskRules      map[string][]*MaskRule
+	maskKey        []byte
+}
+
+// processors returns the filters of the table
+func (r *recordFilters) processors(table Table) []Processor {
+	var result []Processor
+	if drop := r.dropEventTypes.Values(table); len(drop) > 0 {
+		result = append(result, &EventTypeFilter{
+			Drop: drop,
+		})
+	}
+	if expressions := r.expressions(table); len(expressions) > 0 {
+		result = append(result, &RowFilter{
+			Expressions: expressions,
+		})
+	}
+	include, exclude := r.includeColumns.Values(table), r.excludeColumns.Values(table)
+	if len(include) > 0 || len(exclude) > 0 {
+		result = append(result, &ColumnFilter{
+			Include: include,
+			Exclude: exclude,
+		})
+	}
+	if rules := r.masks(table); len(rules) > 0 {
+		result = append(result, &Masker{
+			Rules: rules,
+			Key:   r.maskKey,
+		})
+	}
+	return result
+}
+
+// expressions returns the row filters of all patterns matching the table, ordered by pattern
+func (r *recordFilters) expressions(table Table) []*Expression {
+	var patterns []string
+	for pattern := range r.rowFilters {
+		patterns = append(patterns, pattern)
+	}
+	var result []*Expression
+	for _, pattern := range matchingPatterns(patterns, table) {
+		result = append(result, r.rowFilters[pattern])
+	}
+	return result
+}
+
+// masks returns the mask rules of all patterns matching the table, ordered by pattern
+func (r *recordFilters) masks(table Table) []*MaskRule {
+	var patterns []string
+	for pattern := range r.maskRules {
+		patterns = append(patterns, pattern)
+	}
+	var result []*MaskRule
+	for _, pattern := range matchingPatterns(patterns, table) {
+		result = append(result, r.maskRules[pattern]...)
+	}
+	return result
+}
+
+// matchingPatterns returns the patterns that match the table in sorted order
+func matchingPatterns(patterns []string, table Table) []string {
+	var result []string
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, table.String()); ok {
+			result = append(result, pattern)
 		}
 	}
-	gtidExtractor := &GTIDExtractor{
-		Format: a.CdcFormat,
+	sort.Strings(result)
+	return result
+}
+
+// minMaskKeyLength prevents guessing the key of hashed values
+const minMaskKeyLength = 16
+
+func (a *App) recordFilters() (*recordFilters, error) {
+	dropEventTypes, err := ParseTableLists(a.DropEventTypes)
+	if err != nil {
+		return nil, errors.Wrap(err, "DropEventTypes invalid")
+	}
+	for _, types := range dropEventTypes {
+		if err := ValidateEventTypes(types); err != nil {
+			return nil, errors.Wrap(err, "DropEventTypes invalid")
+		}
+	}
+	includeColumns, err := ParseTableLists(a.IncludeColumns)
+	if err != nil {
+		return nil, errors.Wrap(err, "IncludeColumns invalid")
+	}
+	excludeColumns, err := ParseTableLists(a.ExcludeColumns)
+	if err != nil {
+		return nil, errors.Wrap(err, "ExcludeColumns invalid")
 	}
-	streamer := &Streamer{
-		GTID: gtid,
+	for _, lists := range []TableLists{includeColumns, excludeColumns} {
+		for _, columns := range lists {
+			if err := ValidateColumnPatterns(columns); err != nil {
+				return nil, errors.Wrap(err, "IncludeColumns or ExcludeColumns invalid")
+			}
+		}
+	}
+	rowFilters, err := ParseRowFilters(a.RowFilters)
+	if err != nil {
+		return nil, errors.Wrap(err, "RowFilters invalid")
+	}
+	maskRules, err := ParseMaskRules(a.MaskColumns)
+	if err != nil {
+		return nil, errors.Wrap(err, "MaskColumns invalid")
+	}
+	for _, rules := range maskRules {
+		for _, rule := range rules {
+			if (rule.Action == MaskHash || rule.Action == MaskTokenize) && len(a.MaskKey) < minMaskKeyLength {
+				return nil, errors.Errorf("MaskKey with at least %d characters required by %s", minMaskKeyLength, rule.Action)
+			}
+		}
+	}
+	return &recordFilters{
+		dropEventTypes: dropEventTypes,
+		includeColumns: includeColumns,
+		excludeColumns: excludeColumns,
+		rowFilters:     rowFilters,
+		maskRules:      maskRules,
+		maskKey:        []byte(a.MaskKey),
+	}, nil
+}
+
+// tableRunner streams the given table until the context is canceled.
+// Failures only restart the stream of this table and resume at the last stored checkpoint.
+func (a *App) tableRunner(table Table, checkpoint *Checkpoint, deps *dependencies) run.RunFunc {
+	return func(ctx context.Context) error {
+		if err := a.validateTableKeyColumns(table, deps.keyColumns); err != nil {
+			return err
+		}
+		gtidStore := a.checkpointStore(table, deps)
+		if checkpoint == nil {
+			var err error
+			checkpoint, err = a.startCheckpoint(table, gtidStore, deps)
+			if err != nil {
+				return errors.Wrapf(err, "get start position of %s failed", table)
+			}
+		}
+		if checkpoint == nil {
+			checkpoint = &Checkpoint{}
+		}
+		for {
+			if err := a.createStreamer(table, checkpoint, gtidStore, deps).Run(ctx); err != nil {
+				glog.Warningf("stream %s failed: %v", table, err)
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(retryDelay):
+				glog.V(3).Infof("streamer of %s closed => restart", table)
+			}
+			stored, err := readCheckpoint(gtidStore, table)
+			if err != nil {
+				glog.Warningf("%v", err)
+			}
+			if stored != nil {
+				checkpoint = stored
+			}
+		}
+	}
+}
+
+// startCheckpoint returns the checkpoint to start streaming from depending on the GTID recovery mode.
+// Positions recovered from Kafka have no event number, the whole last transaction is sent again.
+func (a *App) startCheckpoint(table Table, gtidStore CheckpointStore, deps *dependencies) (*Checkpoint, error) {
+	switch a.GTIDRecovery {
+	case GTIDRecoveryKafka:
+		return kafkaCheckpoint(table, deps)
+	case GTIDRecoveryFallback:
+		checkpoint, err := readCheckpoint(gtidStore, table)
+		if err != nil || checkpoint != nil {
+			return checkpoint, err
+		}
+		return kafkaCheckpoint(table, deps)
+	case GTIDRecoveryCheck:
+		checkpoint, err := readCheckpoint(gtidStore, table)
+		if err != nil {
+			return nil, err
+		}
+		lastCheckpoint, err := kafkaCheckpoint(table, deps)
+		if err != nil {
+			return nil, err
+		}
+		var position GTIDPosition
+		if checkpoint != nil {
+			position = checkpoint.Position
+		}
+		// the stored position lags behind with a flush policy and the last messages may miss domains,
+		// only a stored transaction later than the one in kafka is a mismatch
+		if position.Ahead(lastCheckpoint.Position) {
+			return nil, errors.Errorf("stored position %s is ahead of position %s in kafka", position, lastCheckpoint.Position)
+		}
+		return checkpoint, nil
+	default:
+		return readCheckpoint(gtidStore, table)
+	}
+}
+
+func kafkaCheckpoint(table Table, deps *dependencies) (*Checkpoint, error) {
+	topic, err := deps.topicRouter.TableTopic(table)
+	if err != nil {
+		return nil, err
+	}
+	position, err := deps.gtidReader.LastPosition(topic)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read last position of topic %s failed", topic)
+	}
+	glog.V(1).Infof("found position %s of %s in topic %s", position, table, topic)
+	return &Checkpoint{Position: position}, nil
+}
+
+// readCheckpoint returns the stored checkpoint of the table or nil if none was stored yet
+func readCheckpoint(gtidStore CheckpointStore, table Table) (*Checkpoint, error) {
+	checkpoint, err := gtidStore.Read()
+	if cause := errors.Cause(err); cause == ErrNoCheckpoint || os.IsNotExist(cause) {
+		glog.V(1).Infof("no checkpoint of %s stored", table)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "read checkpoint of %s failed", table)
+	}
+	return checkpoint, nil
+}
+
+// checkpointStore returns the store of the table for the configured backend and flush policy
+func (a *App) checkpointStore(table Table, deps *dependencies) CheckpointStore {
+	store := a.checkpointBackend(table, deps)
+	if a.CheckpointFlushEvents <= 1 && a.CheckpointFlushInterval == 0 && !a.CheckpointFlushTransaction {
+		return store
+	}
+	return &FlushingCheckpointStore{
+		Store:          store,
+		Events:         a.CheckpointFlushEvents,
+		Interval:       a.CheckpointFlushInterval,
+		TransactionEnd: a.CheckpointFlushTransaction,
+	}
+}
+
+func (a *App) checkpointBackend(table Table, deps *dependencies) CheckpointStore {
+	switch a.CheckpointStore {
+	case CheckpointStoreKafka:
+		return &KafkaCheckpointStore{
+			Producer: deps.producer,
+			Client:   deps.client,
+			Topic:    a.CheckpointTopic,
+			UUID:     a.CdcUUID,
+			Table:    table,
+		}
+	case CheckpointStoreMemory:
+		return &MemoryCheckpointStore{}
+	default:
+		return &GTIDStore{
+			DataDir: a.DataDir,
+			Table:   table,
+			Legacy:  table == Table{Database: a.CdcDatabase, Name: a.CdcTable},
+		}
+	}
+}
+
+func (a *App) createStreamer(table Table, checkpoint *Checkpoint, gtidStore CheckpointStore, deps *dependencies) *Streamer {
+	position := checkpoint.Position
+	processors := []Processor{
+		&SchemaTracker{
+			History: deps.schemaHistory,
+		},
+		&EventDeduplicator{
+			GTID:        position.Last(),
+			EventNumber: checkpoint.EventNumber,
+		},
+	}
+	if len(position) > 1 {
+		processors = append(processors, &PositionFilter{
+			Position: position,
+		})
+	}
+	outbox := a.outboxMatcher().Match(table)
+	if outbox {
+		processors = append(processors, a.outboxFilter())
+	}
+	if deps.filters != nil {
+		processors = append(processors, deps.filters.processors(table)...)
+	}
+	if a.kafkaFormat() != a.CdcFormat {
+		processors = append(processors, &Transcoder{
+			Format: a.kafkaFormat(),
+		})
+	}
+	if a.KafkaEnvelope == EnvelopeDebezium {
+		processors = append(processors, &UpdateMerger{})
+	}
+	sender := &KafkaSender{
+		Producer:           deps.producer,
+		TopicRouter:        deps.topicRouter,
+		GTIDStore:          gtidStore,
+		Position:           position,
+		ValueEncoder:       deps.valueEncoder,
+		KeyEncoder:         deps.keyEncoder,
+		HeaderEncoders:     deps.headerEncoders,
+		Tombstones:         a.tombstoneMatcher().Match(table),
+		Transactions:       a.KafkaTransactions,
+		TransactionTimeout: a.KafkaTransactionTimeout,
+		TransactionEnd:     a.KafkaTransactionEnd,
+	}
+	if outbox {
+		a.outbox(sender, deps.topicRouter)
+	}
+	if deps.asyncClient != nil {
+		sender.NewAsyncProducer = func() (sarama.AsyncProducer, error) {
+			return sarama.NewAsyncProducerFromClient(deps.asyncClient)
+		}
+	}
+	return &Streamer{
+		GTID: position.Last(),
 		Reader: &RetryReader{
-			GTIDExtractor: gtidExtractor,
 			Reader: &MaxscaleReader{
 				Dialer: &TcpDialer{
 					Address: fmt.Sprintf("%s:%d", a.CdcHost, a.CdcPort),
 				},
 				User:     a.CdcUser,
 				Password: a.CdcPassword,
-				Database: a.CdcDatabase,
-				Table:    a.CdcTable,
+				Database: table.Database,
+				Table:    table.Name,
 				Format:   a.CdcFormat,
 				UUID:     a.CdcUUID,
 			},
 		},
-		Sender: &KafkaSender{
-			KafkaBrokers:  a.KafkaBrokers,
-			KafkaTopic:    a.KafkaTopic,
-			GTIDStore:     gtidStore,
-			GTIDExtractor: gtidExtractor,
-		},
+		Processors: processors,
+		Sender:     sender,
 	}
-	return streamer.Run(ctx)
 }
 
 func (a *App) runHttpServer(ctx context.Context) error {
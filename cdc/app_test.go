@@ -5,6 +5,8 @@
 package cdc_test
 
 import (
+	"time"
+
 	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -47,6 +49,40 @@ var _ = Describe("CDC App", func() {
 		app.CdcFormat = "AVRO"
 		Expect(app.Validate()).NotTo(HaveOccurred())
 	})
+	It("Validate returns error if SchemaRegistryURL is set with CdcFormat JSON", func() {
+		app.SchemaRegistryURL = "http://schema-registry:8081"
+		app.SchemaRegistrySubjectNameStrategy = cdc.TopicNameStrategy
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if SchemaRegistryURL is set with CdcFormat AVRO", func() {
+		app.CdcFormat = "AVRO"
+		app.SchemaRegistryURL = "http://schema-registry:8081"
+		app.SchemaRegistrySubjectNameStrategy = cdc.TopicNameStrategy
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns no error if SchemaRegistryURL is set with KafkaFormat AVRO", func() {
+		app.KafkaFormat = "AVRO"
+		app.SchemaRegistryURL = "http://schema-registry:8081"
+		app.SchemaRegistrySubjectNameStrategy = cdc.TopicNameStrategy
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if SchemaRegistryURL is set with KafkaFormat JSON", func() {
+		app.CdcFormat = "AVRO"
+		app.KafkaFormat = "JSON"
+		app.SchemaRegistryURL = "http://schema-registry:8081"
+		app.SchemaRegistrySubjectNameStrategy = cdc.TopicNameStrategy
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if KafkaFormat is invalid", func() {
+		app.KafkaFormat = "banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if SchemaRegistrySubjectNameStrategy is invalid", func() {
+		app.CdcFormat = "AVRO"
+		app.SchemaRegistryURL = "http://schema-registry:8081"
+		app.SchemaRegistrySubjectNameStrategy = "banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
 	It("Validate returns error if CdcHost is empty", func() {
 		app.CdcHost = ""
 		Expect(app.Validate()).To(HaveOccurred())
@@ -63,6 +99,53 @@ var _ = Describe("CDC App", func() {
 		app.CdcTable = ""
 		Expect(app.Validate()).To(HaveOccurred())
 	})
+	It("Validate returns no error if CdcTables is set instead of CdcDatabase and CdcTable", func() {
+		app.CdcDatabase = ""
+		app.CdcTable = ""
+		app.CdcTables = "mydb.a,mydb.b"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns no error if CdcTables and CdcTable is set", func() {
+		app.CdcTables = "mydb.a,mydb.b"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if CdcTables is invalid", func() {
+		app.CdcTables = "banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if CdcTable and CdcTables is empty", func() {
+		app.CdcDatabase = ""
+		app.CdcTable = ""
+		app.CdcTables = ""
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if CdcTables contains pattern without CdcAvroDir", func() {
+		app.CdcTables = "mydb.order_*"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if CdcTables contains pattern with CdcAvroDir", func() {
+		app.CdcTables = "mydb.order_*"
+		app.CdcAvroDir = "/var/lib/maxscale"
+		app.CdcDiscoveryInterval = time.Minute
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns no error if only CdcDatabase is set with CdcAvroDir", func() {
+		app.CdcTable = ""
+		app.CdcAvroDir = "/var/lib/maxscale"
+		app.CdcDiscoveryInterval = time.Minute
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if CdcDiscoveryInterval is 0 with CdcAvroDir", func() {
+		app.CdcAvroDir = "/var/lib/maxscale"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if CdcExcludeTables is invalid", func() {
+		app.CdcTables = "mydb.order_*"
+		app.CdcExcludeTables = "mydb.[banana"
+		app.CdcAvroDir = "/var/lib/maxscale"
+		app.CdcDiscoveryInterval = time.Minute
+		Expect(app.Validate()).To(HaveOccurred())
+	})
 	It("Validate returns error if CdcUser is empty", func() {
 		app.CdcUser = ""
 		Expect(app.Validate()).To(HaveOccurred())
@@ -79,6 +162,327 @@ var _ = Describe("CDC App", func() {
 		app.KafkaTopic = ""
 		Expect(app.Validate()).To(HaveOccurred())
 	})
+	It("Validate returns no error if KafkaTopic is a template", func() {
+		app.KafkaTopic = "cdc.{database}.{table}.{event_type}"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns no error if KafkaTopic contains declared column placeholder", func() {
+		app.KafkaTopic = "orders.tenant-{tenant_id}"
+		app.KafkaTopicColumns = "tenant_id"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if KafkaTopic contains unknown placeholder", func() {
+		app.KafkaTopic = "cdc.{database}.{tabel}"
+		app.KafkaTopicColumns = "tenant_id"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if KafkaTopicColumns is invalid", func() {
+		app.KafkaTopicColumns = "tenant-id"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error for KafkaTopicMappings and KafkaTopicDefault", func() {
+		app.KafkaTopic = "orders.tenant-{tenant_id}"
+		app.KafkaTopicColumns = "tenant_id"
+		app.KafkaTopicMappings = "tenant_id=42:acme+7:globex"
+		app.KafkaTopicDefault = "{database}.{table}.dlq"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if KafkaTopicMappings is invalid", func() {
+		app.KafkaTopicMappings = "tenant_id=42"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if KafkaTopicDefault depends on values", func() {
+		app.KafkaTopicDefault = "orders.{tenant_id}"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error for OutboxTables", func() {
+		app.OutboxTables = "shop.outbox"
+		app.OutboxTopic = "events.{aggregate_type}"
+		app.OutboxTombstones = true
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if OutboxTables is used with GTIDRecovery", func() {
+		app.OutboxTables = "shop.outbox"
+		app.GTIDRecovery = cdc.GTIDRecoveryFallback
+		app.KafkaTopic = "cdc.{database}.{table}"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if OutboxTopic is malformed", func() {
+		app.OutboxTables = "shop.outbox"
+		app.OutboxTopic = "events.{aggregate_type"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if KafkaTopic contains malformed placeholder", func() {
+		app.KafkaTopic = "cdc.{banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if KafkaTopicOverrides is valid", func() {
+		app.KafkaTopicOverrides = "mydb.a=topic-a,mydb.b=topic-b.{event_type}"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if KafkaTopicOverrides is invalid", func() {
+		app.KafkaTopicOverrides = "banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if KafkaKey is json with KafkaKeyColumns", func() {
+		app.KafkaKey = cdc.KeyFormatJSON
+		app.KafkaKeyColumns = "mydb.mytable=id,mydb.b=order_id+item_id"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if KafkaKey is json without KafkaKeyColumns of table", func() {
+		app.KafkaKey = cdc.KeyFormatJSON
+		app.KafkaKeyColumns = "mydb.a=id"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if KafkaKey is json without KafkaKeyColumns of outbox table", func() {
+		app.KafkaKey = cdc.KeyFormatJSON
+		app.OutboxTables = "mydb.mytable"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if KafkaKey is invalid", func() {
+		app.KafkaKey = "banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if KafkaKeyColumns is invalid", func() {
+		app.KafkaKey = cdc.KeyFormatString
+		app.KafkaKeyColumns = "banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if KafkaKeyColumns is set with KafkaKey gtid", func() {
+		app.KafkaKey = cdc.KeyFormatGTID
+		app.KafkaKeyColumns = "mydb.a=id"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if KafkaTombstones is set with KafkaKey", func() {
+		app.KafkaKey = cdc.KeyFormatString
+		app.KafkaKeyColumns = "mydb.mytable=id"
+		app.KafkaTombstones = "mydb.a,shop.*"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if KafkaTombstones is set without KafkaKey", func() {
+		app.KafkaTombstones = "mydb.a"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if KafkaTombstones is invalid", func() {
+		app.KafkaKey = cdc.KeyFormatString
+		app.KafkaTombstones = "shop.["
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if KafkaTransactionEnd is set with KafkaTransactions", func() {
+		app.KafkaTransactions = true
+		app.KafkaTransactionEnd = true
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if KafkaTransactionEnd is set without KafkaTransactions", func() {
+		app.KafkaTransactionEnd = true
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if KafkaTransactionEnd is set with KafkaFormat AVRO", func() {
+		app.KafkaTransactions = true
+		app.KafkaTransactionEnd = true
+		app.KafkaFormat = "AVRO"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if KafkaTransactionTimeout is negative", func() {
+		app.KafkaTransactionTimeout = -time.Second
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if KafkaAsync is set with options", func() {
+		app.KafkaAsync = true
+		app.KafkaBatchSize = 1000
+		app.KafkaLinger = 5 * time.Millisecond
+		app.KafkaMaxInFlight = 5
+		app.KafkaCompression = "lz4"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if KafkaBatchSize is set without KafkaLinger", func() {
+		app.KafkaAsync = true
+		app.KafkaBatchSize = 1000
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if KafkaCompression is zstd", func() {
+		app.KafkaCompression = "zstd"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if KafkaBatchSize is negative", func() {
+		app.KafkaBatchSize = -1
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if KafkaEnvelope is debezium", func() {
+		app.KafkaEnvelope = cdc.EnvelopeDebezium
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if KafkaEnvelope is debezium with KafkaFormat AVRO", func() {
+		app.KafkaEnvelope = cdc.EnvelopeDebezium
+		app.KafkaFormat = "AVRO"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if KafkaEnvelope is cloudevents", func() {
+		app.KafkaEnvelope = cdc.EnvelopeCloudEvents
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if KafkaEnvelope is structured cloudevents with KafkaFormat AVRO", func() {
+		app.KafkaEnvelope = cdc.EnvelopeCloudEvents
+		app.KafkaCloudEventsMode = cdc.CloudEventsStructured
+		app.KafkaFormat = "AVRO"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if KafkaEnvelope is binary cloudevents with KafkaFormat AVRO", func() {
+		app.KafkaEnvelope = cdc.EnvelopeCloudEvents
+		app.KafkaCloudEventsMode = cdc.CloudEventsBinary
+		app.KafkaFormat = "AVRO"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if KafkaCloudEventsMode is invalid", func() {
+		app.KafkaEnvelope = cdc.EnvelopeCloudEvents
+		app.KafkaCloudEventsMode = "banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error for record filters", func() {
+		app.DropEventTypes = "shop.*=update_before+delete"
+		app.IncludeColumns = "shop.order=id+status"
+		app.ExcludeColumns = "shop.*=blob_*"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if DropEventTypes contains unknown event type", func() {
+		app.DropEventTypes = "shop.*=banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if ExcludeColumns is invalid", func() {
+		app.ExcludeColumns = "shop.*=["
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error for row filters", func() {
+		app.RowFilters = `shop.orders=tenant_id == 42 && status != "draft"`
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if RowFilters is invalid", func() {
+		app.RowFilters = `shop.orders=tenant_id ==`
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error for mask rules with key", func() {
+		app.MaskColumns = "shop.customers=email:hash+phone:truncate:4+ssn:tokenize"
+		app.MaskKey = "0123456789abcdef"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if MaskKey is missing for hash", func() {
+		app.MaskColumns = "shop.customers=email:hash"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error for redact without MaskKey", func() {
+		app.MaskColumns = "shop.customers=email:redact"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if MaskColumns is invalid", func() {
+		app.MaskColumns = "shop.customers=email:banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if KafkaEnvelope is invalid", func() {
+		app.KafkaEnvelope = "banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if GTIDRecovery is fallback", func() {
+		app.GTIDRecovery = cdc.GTIDRecoveryFallback
+		app.KafkaTopic = "cdc.{database}.{table}"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns no error if GTIDRecovery is set for a single table with static topic", func() {
+		app.GTIDRecovery = cdc.GTIDRecoveryKafka
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if GTIDRecovery is set and tables share a topic", func() {
+		app.GTIDRecovery = cdc.GTIDRecoveryKafka
+		app.CdcTables = "mydb.other"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if GTIDRecovery is set and tables have overrides", func() {
+		app.GTIDRecovery = cdc.GTIDRecoveryKafka
+		app.CdcTables = "mydb.other"
+		app.KafkaTopicOverrides = "mydb.other=other"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if GTIDRecovery is set with discovered tables and static topic", func() {
+		app.GTIDRecovery = cdc.GTIDRecoveryKafka
+		app.CdcTable = ""
+		app.CdcAvroDir = "/var/lib/maxscale"
+		app.CdcDiscoveryInterval = time.Minute
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if GTIDRecovery is set with discovered tables and topic per table", func() {
+		app.GTIDRecovery = cdc.GTIDRecoveryKafka
+		app.CdcTable = ""
+		app.KafkaTopic = "cdc.{database}.{table}"
+		app.CdcAvroDir = "/var/lib/maxscale"
+		app.CdcDiscoveryInterval = time.Minute
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if GTIDRecovery is invalid", func() {
+		app.GTIDRecovery = "banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if GTIDRecovery is set and KafkaTopic depends on values", func() {
+		app.GTIDRecovery = cdc.GTIDRecoveryKafka
+		app.KafkaTopic = "cdc.{database}.{table}.{event_type}"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if GTIDRecovery is set and KafkaTopicOverrides depends on values", func() {
+		app.GTIDRecovery = cdc.GTIDRecoveryCheck
+		app.KafkaTopicOverrides = "mydb.a=a.{domain}"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if GTIDRecovery is set with primary key and AVRO", func() {
+		app.GTIDRecovery = cdc.GTIDRecoveryKafka
+		app.KafkaKey = cdc.KeyFormatJSON
+		app.CdcFormat = "AVRO"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if CheckpointStore is kafka without DataDir", func() {
+		app.CheckpointStore = cdc.CheckpointStoreKafka
+		app.CheckpointTopic = "checkpoints"
+		app.DataDir = ""
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if CheckpointStore is kafka without CheckpointTopic", func() {
+		app.CheckpointStore = cdc.CheckpointStoreKafka
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if CheckpointStore is kafka without CdcUUID", func() {
+		app.CheckpointStore = cdc.CheckpointStoreKafka
+		app.CheckpointTopic = "checkpoints"
+		app.CdcUUID = ""
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if CheckpointStore is memory without DataDir", func() {
+		app.CheckpointStore = cdc.CheckpointStoreMemory
+		app.DataDir = ""
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if CheckpointStore is invalid", func() {
+		app.CheckpointStore = "banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error with checkpoint flush policy", func() {
+		app.CheckpointFlushEvents = 100
+		app.CheckpointFlushInterval = time.Second
+		app.CheckpointFlushTransaction = true
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if CheckpointFlushEvents is negative", func() {
+		app.CheckpointFlushEvents = -1
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns error if CheckpointFlushInterval is negative", func() {
+		app.CheckpointFlushInterval = -time.Second
+		Expect(app.Validate()).To(HaveOccurred())
+	})
+	It("Validate returns no error if CdcGTID is a position", func() {
+		app.CdcGTID = "0-1-58,1-2-10"
+		Expect(app.Validate()).NotTo(HaveOccurred())
+	})
+	It("Validate returns error if CdcGTID is invalid", func() {
+		app.CdcGTID = "banana"
+		Expect(app.Validate()).To(HaveOccurred())
+	})
 	It("Validate returns error if Port is 0", func() {
 		app.Port = 0
 		Expect(app.Validate()).To(HaveOccurred())
@@ -0,0 +1,172 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// AsyncProducerConfig defines batching and compression of the async producer
+type AsyncProducerConfig struct {
+	// BatchSize is the number of messages that triggers a flush, 0 flushes as fast as possible.
+	// A batch size requires a linger, sarama never flushes a partial batch otherwise.
+	BatchSize int
+	// Linger is the time messages wait for more messages of the batch
+	Linger time.Duration
+	// MaxInFlight is the number of requests sent to a broker without waiting for the response.
+	// Retries are disabled for more than one, a retried request could overtake the messages of a key.
+	MaxInFlight int
+	// Compression is none, gzip, snappy or lz4
+	Compression string
+}
+
+// ParseCompression returns the sarama codec of the given compression
+func ParseCompression(compression string) (sarama.CompressionCodec, error) {
+	switch strings.ToLower(compression) {
+	case "", "none":
+		return sarama.CompressionNone, nil
+	case "gzip":
+		return sarama.CompressionGZIP, nil
+	case "snappy":
+		return sarama.CompressionSnappy, nil
+	case "lz4":
+		return sarama.CompressionLZ4, nil
+	case "zstd":
+		return sarama.CompressionNone, errors.New("zstd is not supported yet, sarama v1.19 has no zstd codec")
+	default:
+		return sarama.CompressionNone, errors.Errorf("unknown compression %s", compression)
+	}
+}
+
+// NewAsyncClient returns a client for the async producers of all tables
+func NewAsyncClient(kafkaBrokers string, asyncConfig AsyncProducerConfig) (sarama.Client, error) {
+	if asyncConfig.BatchSize > 0 && asyncConfig.Linger == 0 {
+		return nil, errors.New("batch size requires linger")
+	}
+	compression, err := ParseCompression(asyncConfig.Compression)
+	if err != nil {
+		return nil, err
+	}
+	config := newSaramaConfig()
+	config.Producer.Flush.Messages = asyncConfig.BatchSize
+	config.Producer.Flush.Frequency = asyncConfig.Linger
+	config.Producer.Compression = compression
+	config.Net.MaxOpenRequests = 1
+	if asyncConfig.MaxInFlight > 1 {
+		config.Net.MaxOpenRequests = asyncConfig.MaxInFlight
+		config.Producer.Retry.Max = 0
+	}
+	client, err := sarama.NewClient(strings.Split(kafkaBrokers, ","), config)
+	if err != nil {
+		return nil, errors.Wrap(err, "create async client failed")
+	}
+	return client, nil
+}
+
+// inflight are the messages of a checkpoint that are not acknowledged yet
+type inflight struct {
+	// gtid is nil for messages of a transaction that is not complete yet, they advance no checkpoint
+	gtid        *GTID
+	eventNumber uint64
+	messages    int
+}
+
+// asyncSender sends messages without waiting and writes the checkpoint
+// up to the last record whose messages and all messages before are acknowledged
+type asyncSender struct {
+	producer   sarama.AsyncProducer
+	checkpoint func(gtid *GTID, eventNumber uint64) error
+	pending    []*inflight
+}
+
+// send the messages of a checkpoint, acknowledgements are handled while the input is blocked
+func (a *asyncSender) send(ctx context.Context, messages []*sarama.ProducerMessage, gtid *GTID, eventNumber uint64) error {
+	entry := &inflight{
+		gtid:        gtid,
+		eventNumber: eventNumber,
+		messages:    len(messages),
+	}
+	a.pending = append(a.pending, entry)
+	for _, msg := range messages {
+		msg.Metadata = entry
+		for sent := false; !sent; {
+			select {
+			case <-ctx.Done():
+				return nil
+			case a.producer.Input() <- msg:
+				sent = true
+			case msg := <-a.producer.Successes():
+				if err := a.ack(msg); err != nil {
+					return err
+				}
+			case perr := <-a.producer.Errors():
+				return errors.Wrap(perr.Err, "send message to kafka failed")
+			}
+		}
+	}
+	if len(messages) == 0 {
+		// dropped records have no messages, they are complete once all records before are acknowledged
+		return a.complete()
+	}
+	return nil
+}
+
+// ack the message and write the checkpoint if the oldest pending records are complete
+func (a *asyncSender) ack(msg *sarama.ProducerMessage) error {
+	entry, ok := msg.Metadata.(*inflight)
+	if !ok {
+		return errors.New("acknowledged message without checkpoint")
+	}
+	entry.messages--
+	return a.complete()
+}
+
+// complete writes the checkpoint of the oldest pending records whose messages are all acknowledged
+func (a *asyncSender) complete() error {
+	var last *inflight
+	for len(a.pending) > 0 && a.pending[0].messages == 0 {
+		if a.pending[0].gtid != nil {
+			last = a.pending[0]
+		}
+		a.pending = a.pending[1:]
+	}
+	if last == nil {
+		return nil
+	}
+	return a.checkpoint(last.gtid, last.eventNumber)
+}
+
+// close the producer and checkpoint the messages acknowledged until then
+func (a *asyncSender) close() {
+	a.producer.AsyncClose()
+	successes, failures := a.producer.Successes(), a.producer.Errors()
+	for successes != nil || failures != nil {
+		select {
+		case msg, ok := <-successes:
+			if !ok {
+				successes = nil
+				continue
+			}
+			if err := a.ack(msg); err != nil {
+				glog.Warningf("checkpoint failed: %v", err)
+			}
+		case perr, ok := <-failures:
+			if !ok {
+				failures = nil
+				continue
+			}
+			glog.Warningf("send message to kafka failed: %v", perr.Err)
+		}
+	}
+	if len(a.pending) > 0 {
+		glog.V(2).Infof("%d records not acknowledged, they are sent again after restart", len(a.pending))
+	}
+}
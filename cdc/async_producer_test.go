@@ -0,0 +1,202 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+// asyncProducer passes messages to the test, which acknowledges them in any order
+type asyncProducer struct {
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+}
+
+func newAsyncProducer() *asyncProducer {
+	return &asyncProducer{
+		input:     make(chan *sarama.ProducerMessage, 10),
+		successes: make(chan *sarama.ProducerMessage, 10),
+		errors:    make(chan *sarama.ProducerError, 10),
+	}
+}
+
+func (a *asyncProducer) AsyncClose() {
+	close(a.successes)
+	close(a.errors)
+}
+
+func (a *asyncProducer) Close() error {
+	a.AsyncClose()
+	return nil
+}
+
+func (a *asyncProducer) Input() chan<- *sarama.ProducerMessage {
+	return a.input
+}
+
+func (a *asyncProducer) Successes() <-chan *sarama.ProducerMessage {
+	return a.successes
+}
+
+func (a *asyncProducer) Errors() <-chan *sarama.ProducerError {
+	return a.errors
+}
+
+var _ = Describe("KafkaSender with async producer", func() {
+	var producer *asyncProducer
+	var store *cdc.MemoryCheckpointStore
+	var sender *cdc.KafkaSender
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var ch chan *cdc.Record
+	var done chan error
+
+	checkpoint := func() string {
+		checkpoint, err := store.Read()
+		if err != nil {
+			return err.Error()
+		}
+		return checkpoint.String()
+	}
+
+	BeforeEach(func() {
+		producer = newAsyncProducer()
+		store = &cdc.MemoryCheckpointStore{}
+		sender = &cdc.KafkaSender{
+			TopicRouter: &cdc.TopicTemplate{Template: "mytopic"},
+			GTIDStore:   store,
+			NewAsyncProducer: func() (sarama.AsyncProducer, error) {
+				return producer, nil
+			},
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		ch = make(chan *cdc.Record, 10)
+		done = make(chan error, 1)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	send := func() {
+		go func() {
+			done <- sender.Send(ctx, ch)
+		}()
+	}
+
+	It("advances the checkpoint up to the last contiguous acknowledged record", func() {
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`)
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 59, "event_number": 1}`)
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 60, "event_number": 1}`)
+		send()
+		var messages []*sarama.ProducerMessage
+		for i := 0; i < 3; i++ {
+			messages = append(messages, <-producer.input)
+		}
+		Expect(messages[0].Key).To(Equal(sarama.StringEncoder("0-1-58")))
+		Expect(messages[2].Key).To(Equal(sarama.StringEncoder("0-1-60")))
+
+		producer.successes <- messages[1]
+		Consistently(checkpoint, 50*time.Millisecond).Should(Equal(cdc.ErrNoCheckpoint.Error()))
+		producer.successes <- messages[0]
+		Eventually(checkpoint).Should(Equal("0-1-59#1"))
+		producer.successes <- messages[2]
+		Eventually(checkpoint).Should(Equal("0-1-60#1"))
+
+		cancel()
+		Expect(<-done).To(BeNil())
+	})
+
+	It("advances the checkpoint past dropped records after the records before are acknowledged", func() {
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`)
+		record := jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 59, "event_number": 1}`)
+		record.Dropped = true
+		ch <- record
+		send()
+		msg := <-producer.input
+		Consistently(checkpoint, 50*time.Millisecond).Should(Equal(cdc.ErrNoCheckpoint.Error()))
+		producer.successes <- msg
+		Eventually(checkpoint).Should(Equal("0-1-59#1"))
+		Consistently(producer.input, 50*time.Millisecond).ShouldNot(Receive())
+
+		cancel()
+		Expect(<-done).To(BeNil())
+	})
+
+	It("returns error if a message fails", func() {
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`)
+		send()
+		msg := <-producer.input
+		producer.errors <- &sarama.ProducerError{Msg: msg, Err: errors.New("banana")}
+		Expect(<-done).NotTo(BeNil())
+		Expect(checkpoint()).To(Equal(cdc.ErrNoCheckpoint.Error()))
+	})
+
+	It("checkpoints a transaction after all its messages are acknowledged", func() {
+		sender.Transactions = true
+		sender.TransactionTimeout = time.Hour
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`)
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2}`)
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 59, "event_number": 1}`)
+		send()
+		first, second := <-producer.input, <-producer.input
+		producer.successes <- first
+		Consistently(checkpoint, 50*time.Millisecond).Should(Equal(cdc.ErrNoCheckpoint.Error()))
+		producer.successes <- second
+		Eventually(checkpoint).Should(Equal("0-1-58#2"))
+
+		cancel()
+		Expect(<-done).To(BeNil())
+	})
+
+	It("advances no checkpoint for messages of a transaction sent after the timeout", func() {
+		sender.Transactions = true
+		sender.TransactionTimeout = 10 * time.Millisecond
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`)
+		send()
+		producer.successes <- <-producer.input
+		Consistently(checkpoint, 50*time.Millisecond).Should(Equal(cdc.ErrNoCheckpoint.Error()))
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 59, "event_number": 1}`)
+		Eventually(checkpoint).Should(Equal("0-1-58#1"))
+
+		cancel()
+		Expect(<-done).To(BeNil())
+	})
+})
+
+var _ = Describe("ParseCompression", func() {
+	It("returns the codec", func() {
+		for name, codec := range map[string]sarama.CompressionCodec{
+			"none":   sarama.CompressionNone,
+			"gzip":   sarama.CompressionGZIP,
+			"snappy": sarama.CompressionSnappy,
+			"lz4":    sarama.CompressionLZ4,
+		} {
+			result, err := cdc.ParseCompression(name)
+			Expect(err).To(BeNil())
+			Expect(result).To(Equal(codec))
+		}
+	})
+
+	It("returns error for zstd", func() {
+		_, err := cdc.ParseCompression("zstd")
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("NewAsyncClient", func() {
+	It("returns error if batch size is set without linger", func() {
+		_, err := cdc.NewAsyncClient("localhost:9092", cdc.AsyncProducerConfig{BatchSize: 1000})
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,263 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// Checkpoint store backends
+const (
+	CheckpointStoreFile   = "file"
+	CheckpointStoreKafka  = "kafka"
+	CheckpointStoreMemory = "memory"
+)
+
+// ValidCheckpointStore returns true if the given backend is known
+func ValidCheckpointStore(backend string) bool {
+	switch backend {
+	case CheckpointStoreFile, CheckpointStoreKafka, CheckpointStoreMemory:
+		return true
+	default:
+		return false
+	}
+}
+
+// ErrNoCheckpoint is returned by Read if no position was written yet
+var ErrNoCheckpoint = errors.New("no checkpoint")
+
+// Checkpoint is the position of the last sent event
+type Checkpoint struct {
+	Position GTIDPosition
+	// EventNumber of the last sent event within the last GTID of the position, 0 if unknown
+	EventNumber uint64
+}
+
+// ParseCheckpoint parses POSITION or POSITION#EVENT_NUMBER, e.g. 0-1-58,1-2-10#3
+func ParseCheckpoint(checkpoint string) (*Checkpoint, error) {
+	parts := strings.SplitN(checkpoint, "#", 2)
+	position, err := ParseGTIDPosition(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	result := &Checkpoint{Position: position}
+	if len(parts) == 2 {
+		result.EventNumber, err = strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("parse event number of checkpoint %s failed", checkpoint)
+		}
+	}
+	return result, nil
+}
+
+// String returns POSITION#EVENT_NUMBER, without event number if it is unknown
+func (c *Checkpoint) String() string {
+	if c == nil {
+		return ""
+	}
+	if c.EventNumber == 0 {
+		return c.Position.String()
+	}
+	return fmt.Sprintf("%s#%d", c.Position, c.EventNumber)
+}
+
+// CheckpointStore persists the checkpoint sent of a table
+type CheckpointStore interface {
+	Read() (*Checkpoint, error)
+	Write(checkpoint *Checkpoint) error
+}
+
+// MemoryCheckpointStore keeps the checkpoint in memory, it is lost on restart
+type MemoryCheckpointStore struct {
+	mux        sync.Mutex
+	checkpoint *Checkpoint
+}
+
+// Read the last written checkpoint
+func (m *MemoryCheckpointStore) Read() (*Checkpoint, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.checkpoint == nil {
+		return nil, ErrNoCheckpoint
+	}
+	return m.checkpoint, nil
+}
+
+// Write the given checkpoint
+func (m *MemoryCheckpointStore) Write(checkpoint *Checkpoint) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.checkpoint = checkpoint
+	return nil
+}
+
+// KafkaCheckpointStore writes the checkpoint to a compacted topic.
+// The key is the connector UUID and the table, so multiple connectors can share the topic.
+type KafkaCheckpointStore struct {
+	Producer SyncProducer
+	Client   sarama.Client
+	Topic    string
+	UUID     string
+	Table    Table
+	Timeout  time.Duration
+}
+
+// Read the last checkpoint written for the table
+func (k *KafkaCheckpointStore) Read() (*Checkpoint, error) {
+	partitions, err := k.Client.Partitions(k.Topic)
+	if err == sarama.ErrUnknownTopicOrPartition {
+		return nil, ErrNoCheckpoint
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "get partitions of %s failed", k.Topic)
+	}
+	partition, err := sarama.NewHashPartitioner(k.Topic).Partition(&sarama.ProducerMessage{
+		Key: sarama.StringEncoder(k.key()),
+	}, int32(len(partitions)))
+	if err != nil {
+		return nil, errors.Wrap(err, "get partition failed")
+	}
+	consumer, err := sarama.NewConsumerFromClient(k.Client)
+	if err != nil {
+		return nil, errors.Wrap(err, "create consumer failed")
+	}
+	defer consumer.Close()
+	messages, err := readMessages(k.Client, consumer, k.Topic, partition, 0, k.timeout())
+	if err != nil {
+		return nil, err
+	}
+	var value []byte
+	for _, msg := range messages {
+		if string(msg.Key) == k.key() {
+			value = msg.Value
+		}
+	}
+	if value == nil {
+		return nil, ErrNoCheckpoint
+	}
+	glog.V(2).Infof("read checkpoint %s of %s", value, k.key())
+	return ParseCheckpoint(string(value))
+}
+
+// Write the checkpoint to the topic
+func (k *KafkaCheckpointStore) Write(checkpoint *Checkpoint) error {
+	_, _, err := k.Producer.SendMessage(&sarama.ProducerMessage{
+		Topic: k.Topic,
+		Key:   sarama.StringEncoder(k.key()),
+		Value: sarama.StringEncoder(checkpoint.String()),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "write checkpoint to %s failed", k.Topic)
+	}
+	return nil
+}
+
+func (k *KafkaCheckpointStore) key() string {
+	return fmt.Sprintf("%s/%s", k.UUID, k.Table)
+}
+
+func (k *KafkaCheckpointStore) timeout() time.Duration {
+	if k.Timeout <= 0 {
+		return 30 * time.Second
+	}
+	return k.Timeout
+}
+
+// FlushingCheckpointStore buffers written checkpoints and writes them to the Store by a flush policy,
+// so high volume tables don't write the store once per record.
+// Without policy every checkpoint is written immediately.
+type FlushingCheckpointStore struct {
+	Store CheckpointStore
+	// Events flushes after the given number of writes
+	Events int
+	// Interval flushes pending checkpoints at the latest after the given duration
+	Interval time.Duration
+	// TransactionEnd flushes the position of a transaction when the sender ends it by EndTransaction
+	// or at the latest when the next transaction starts
+	TransactionEnd bool
+
+	mux     sync.Mutex
+	pending *Checkpoint
+	count   int
+	timer   *time.Timer
+}
+
+// Read the last written checkpoint, pending or flushed
+func (f *FlushingCheckpointStore) Read() (*Checkpoint, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.pending != nil {
+		return f.pending, nil
+	}
+	return f.Store.Read()
+}
+
+// Write the checkpoint if the flush policy is reached
+func (f *FlushingCheckpointStore) Write(checkpoint *Checkpoint) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.TransactionEnd && f.pending != nil && f.pending.Position.Last().String() != checkpoint.Position.Last().String() {
+		if err := f.flush(); err != nil {
+			return err
+		}
+	}
+	f.pending = checkpoint
+	f.count++
+	if f.count >= f.Events && (f.Events > 0 || !f.policy()) {
+		return f.flush()
+	}
+	if f.Interval > 0 && f.timer == nil {
+		f.timer = time.AfterFunc(f.Interval, func() {
+			if err := f.Flush(); err != nil {
+				glog.Warningf("flush checkpoint failed: %v", err)
+			}
+		})
+	}
+	return nil
+}
+
+// EndTransaction writes the pending checkpoint if the store flushes at the end of each transaction
+func (f *FlushingCheckpointStore) EndTransaction() error {
+	if !f.TransactionEnd {
+		return nil
+	}
+	return f.Flush()
+}
+
+// Flush writes the pending checkpoint to the store
+func (f *FlushingCheckpointStore) Flush() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.flush()
+}
+
+func (f *FlushingCheckpointStore) flush() error {
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	if f.pending == nil {
+		return nil
+	}
+	if err := f.Store.Write(f.pending); err != nil {
+		return err
+	}
+	f.pending = nil
+	f.count = 0
+	return nil
+}
+
+// policy returns true if any flush policy is configured
+func (f *FlushingCheckpointStore) policy() bool {
+	return f.Events > 0 || f.Interval > 0 || f.TransactionEnd
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("MemoryCheckpointStore", func() {
+	It("returns written checkpoint", func() {
+		store := &cdc.MemoryCheckpointStore{}
+		checkpoint, err := cdc.ParseCheckpoint("1-2-3,2-2-4#5")
+		Expect(err).To(BeNil())
+		Expect(store.Write(checkpoint)).To(BeNil())
+		result, err := store.Read()
+		Expect(err).To(BeNil())
+		Expect(result).To(Equal(checkpoint))
+	})
+
+	It("returns ErrNoCheckpoint if nothing was written", func() {
+		store := &cdc.MemoryCheckpointStore{}
+		_, err := store.Read()
+		Expect(err).To(Equal(cdc.ErrNoCheckpoint))
+	})
+})
+
+var _ = Describe("KafkaCheckpointStore", func() {
+	var broker *sarama.MockBroker
+	var client sarama.Client
+	var producer *mocks.SyncProducer
+	var store *cdc.KafkaCheckpointStore
+
+	BeforeEach(func() {
+		fetchResponse := &sarama.FetchResponse{}
+		fetchResponse.AddMessage("checkpoints", 0, sarama.StringEncoder("myuuid/mydb.a"), sarama.StringEncoder("0-1-10"), 0)
+		fetchResponse.AddMessage("checkpoints", 0, sarama.StringEncoder("myuuid/mydb.b"), sarama.StringEncoder("0-1-20"), 1)
+		fetchResponse.AddMessage("checkpoints", 0, sarama.StringEncoder("myuuid/mydb.a"), sarama.StringEncoder("0-1-30#2"), 2)
+		fetchResponse.AddMessage("checkpoints", 0, sarama.StringEncoder("other/mydb.a"), sarama.StringEncoder("0-1-40"), 3)
+
+		broker = sarama.NewMockBroker(GinkgoT(), 1)
+		broker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest": sarama.NewMockMetadataResponse(GinkgoT()).
+				SetBroker(broker.Addr(), broker.BrokerID()).
+				SetLeader("checkpoints", 0, broker.BrokerID()),
+			"OffsetRequest": sarama.NewMockOffsetResponse(GinkgoT()).
+				SetOffset("checkpoints", 0, sarama.OffsetOldest, 0).
+				SetOffset("checkpoints", 0, sarama.OffsetNewest, 4),
+			"FetchRequest": sarama.NewMockWrapper(fetchResponse),
+		})
+		var err error
+		client, err = sarama.NewClient([]string{broker.Addr()}, sarama.NewConfig())
+		Expect(err).To(BeNil())
+		producer = &mocks.SyncProducer{}
+		store = &cdc.KafkaCheckpointStore{
+			Producer: producer,
+			Client:   client,
+			Topic:    "checkpoints",
+			UUID:     "myuuid",
+			Table:    cdc.Table{Database: "mydb", Name: "a"},
+			Timeout:  5 * time.Second,
+		}
+	})
+
+	AfterEach(func() {
+		_ = client.Close()
+		broker.Close()
+	})
+
+	It("writes checkpoint keyed by uuid and table", func() {
+		position, err := cdc.ParseGTIDPosition("0-1-58,1-2-3")
+		Expect(err).To(BeNil())
+		Expect(store.Write(&cdc.Checkpoint{Position: position, EventNumber: 4})).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(1))
+		msg := producer.SendMessageArgsForCall(0)
+		Expect(msg.Topic).To(Equal("checkpoints"))
+		Expect(msg.Key).To(Equal(sarama.StringEncoder("myuuid/mydb.a")))
+		Expect(msg.Value).To(Equal(sarama.StringEncoder("0-1-58,1-2-3#4")))
+	})
+
+	It("returns error if write fails", func() {
+		producer.SendMessageReturns(0, 0, errors.New("banana"))
+		Expect(store.Write(&cdc.Checkpoint{})).NotTo(BeNil())
+	})
+
+	It("reads the last checkpoint of the table", func() {
+		checkpoint, err := store.Read()
+		Expect(err).To(BeNil())
+		Expect(checkpoint.Position.String()).To(Equal("0-1-30"))
+		Expect(checkpoint.EventNumber).To(Equal(uint64(2)))
+	})
+
+	It("returns ErrNoCheckpoint for unknown table", func() {
+		store.Table = cdc.Table{Database: "mydb", Name: "c"}
+		_, err := store.Read()
+		Expect(err).To(Equal(cdc.ErrNoCheckpoint))
+	})
+
+	It("resumes from the written checkpoint after a restart with the same uuid", func() {
+		checkpoint, err := cdc.ParseCheckpoint("0-1-58,1-2-3#4")
+		Expect(err).To(BeNil())
+		Expect(store.Write(checkpoint)).To(BeNil())
+		msg := producer.SendMessageArgsForCall(0)
+
+		fetchResponse := &sarama.FetchResponse{}
+		fetchResponse.AddMessage("checkpoints", 0, msg.Key, msg.Value, 0)
+		restarted := sarama.NewMockBroker(GinkgoT(), 2)
+		defer restarted.Close()
+		restarted.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest": sarama.NewMockMetadataResponse(GinkgoT()).
+				SetBroker(restarted.Addr(), restarted.BrokerID()).
+				SetLeader("checkpoints", 0, restarted.BrokerID()),
+			"OffsetRequest": sarama.NewMockOffsetResponse(GinkgoT()).
+				SetOffset("checkpoints", 0, sarama.OffsetOldest, 0).
+				SetOffset("checkpoints", 0, sarama.OffsetNewest, 1),
+			"FetchRequest": sarama.NewMockWrapper(fetchResponse),
+		})
+		restartedClient, err := sarama.NewClient([]string{restarted.Addr()}, sarama.NewConfig())
+		Expect(err).To(BeNil())
+		defer restartedClient.Close()
+
+		result, err := (&cdc.KafkaCheckpointStore{
+			Producer: &mocks.SyncProducer{},
+			Client:   restartedClient,
+			Topic:    "checkpoints",
+			UUID:     "myuuid",
+			Table:    cdc.Table{Database: "mydb", Name: "a"},
+			Timeout:  5 * time.Second,
+		}).Read()
+		Expect(err).To(BeNil())
+		Expect(result).To(Equal(checkpoint))
+	})
+})
+
+var _ = Describe("FlushingCheckpointStore", func() {
+	var backend *cdc.MemoryCheckpointStore
+	var store *cdc.FlushingCheckpointStore
+
+	gtid := func(sequence uint64) *cdc.Checkpoint {
+		return &cdc.Checkpoint{Position: cdc.GTIDPosition{{Domain: 0, ServerId: 1, Sequence: sequence}}}
+	}
+
+	BeforeEach(func() {
+		backend = &cdc.MemoryCheckpointStore{}
+		store = &cdc.FlushingCheckpointStore{Store: backend}
+	})
+
+	It("writes every checkpoint without policy", func() {
+		Expect(store.Write(gtid(1))).To(BeNil())
+		Expect(backend.Read()).To(Equal(gtid(1)))
+	})
+
+	It("writes every N events", func() {
+		store.Events = 3
+		Expect(store.Write(gtid(1))).To(BeNil())
+		Expect(store.Write(gtid(2))).To(BeNil())
+		_, err := backend.Read()
+		Expect(err).To(Equal(cdc.ErrNoCheckpoint))
+		Expect(store.Write(gtid(3))).To(BeNil())
+		Expect(backend.Read()).To(Equal(gtid(3)))
+	})
+
+	It("writes on transaction end", func() {
+		store.TransactionEnd = true
+		Expect(store.Write(gtid(1))).To(BeNil())
+		Expect(store.Write(gtid(1))).To(BeNil())
+		_, err := backend.Read()
+		Expect(err).To(Equal(cdc.ErrNoCheckpoint))
+		Expect(store.Write(gtid(2))).To(BeNil())
+		Expect(backend.Read()).To(Equal(gtid(1)))
+	})
+
+	It("writes on end of transaction", func() {
+		store.TransactionEnd = true
+		Expect(store.Write(gtid(1))).To(BeNil())
+		_, err := backend.Read()
+		Expect(err).To(Equal(cdc.ErrNoCheckpoint))
+		Expect(store.EndTransaction()).To(BeNil())
+		Expect(backend.Read()).To(Equal(gtid(1)))
+	})
+
+	It("ignores end of transaction without transaction policy", func() {
+		store.Events = 100
+		Expect(store.Write(gtid(1))).To(BeNil())
+		Expect(store.EndTransaction()).To(BeNil())
+		_, err := backend.Read()
+		Expect(err).To(Equal(cdc.ErrNoCheckpoint))
+	})
+
+	It("writes after interval", func() {
+		store.Interval = 10 * time.Millisecond
+		Expect(store.Write(gtid(1))).To(BeNil())
+		Eventually(func() (*cdc.Checkpoint, error) {
+			return backend.Read()
+		}).Should(Equal(gtid(1)))
+	})
+
+	It("writes pending checkpoint on flush", func() {
+		store.Events = 100
+		Expect(store.Write(gtid(1))).To(BeNil())
+		Expect(store.Flush()).To(BeNil())
+		Expect(backend.Read()).To(Equal(gtid(1)))
+	})
+
+	It("returns pending checkpoint on read", func() {
+		store.Events = 100
+		Expect(store.Write(gtid(1))).To(BeNil())
+		Expect(store.Read()).To(Equal(gtid(1)))
+	})
+})
+
+var _ = Describe("Checkpoint", func() {
+	It("parses position with event number", func() {
+		checkpoint, err := cdc.ParseCheckpoint("0-1-58,1-2-10#3")
+		Expect(err).To(BeNil())
+		Expect(checkpoint.Position.String()).To(Equal("0-1-58,1-2-10"))
+		Expect(checkpoint.EventNumber).To(Equal(uint64(3)))
+		Expect(checkpoint.String()).To(Equal("0-1-58,1-2-10#3"))
+	})
+
+	It("parses position without event number", func() {
+		checkpoint, err := cdc.ParseCheckpoint("0-1-58")
+		Expect(err).To(BeNil())
+		Expect(checkpoint.EventNumber).To(Equal(uint64(0)))
+		Expect(checkpoint.String()).To(Equal("0-1-58"))
+	})
+
+	It("returns error for invalid event number", func() {
+		_, err := cdc.ParseCheckpoint("0-1-58#banana")
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,130 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+)
+
+// Modes of the CloudEvents Kafka protocol binding
+const (
+	CloudEventsStructured = "structured"
+	CloudEventsBinary     = "binary"
+)
+
+// ValidCloudEventsMode returns true if the given mode is known
+func ValidCloudEventsMode(mode string) bool {
+	return mode == CloudEventsStructured || mode == CloudEventsBinary
+}
+
+// cloudEventsTypePrefix is prepended to the event type of the record
+const cloudEventsTypePrefix = "maxscale.cdc."
+
+// CloudEventsEncoder wraps records as CloudEvents 1.0.
+// In structured mode the value is the JSON event with the record as data,
+// in binary mode the value is the record and the attributes are ce_ headers.
+type CloudEventsEncoder struct {
+	Mode string
+	// Host of Maxscale, part of the source
+	Host string
+	// ValueEncoder is optional, it encodes the record in binary mode
+	ValueEncoder ValueEncoder
+}
+
+// cloudEvent contains the attributes of the event
+type cloudEvent struct {
+	id          string
+	source      string
+	eventType   string
+	time        string
+	contentType string
+}
+
+// Encode the value of the message
+func (c *CloudEventsEncoder) Encode(topic string, record *Record) ([]byte, error) {
+	if c.Mode == CloudEventsBinary {
+		if c.ValueEncoder == nil {
+			return record.Data, nil
+		}
+		return c.ValueEncoder.Encode(topic, record)
+	}
+	if record.Format != "JSON" {
+		return nil, errors.Errorf("structured mode requires JSON but got %s", record.Format)
+	}
+	event, err := c.event(record)
+	if err != nil {
+		return nil, err
+	}
+	return encodeJSONObject(
+		[]string{"specversion", "id", "source", "type", "time", "datacontenttype", "data"},
+		map[string]interface{}{
+			"specversion":     "1.0",
+			"id":              event.id,
+			"source":          event.source,
+			"type":            event.eventType,
+			"time":            event.time,
+			"datacontenttype": event.contentType,
+			"data":            json.RawMessage(bytes.TrimSpace(record.Data)),
+		},
+	)
+}
+
+// Headers returns the content type in structured mode and all attributes in binary mode
+func (c *CloudEventsEncoder) Headers(topic string, record *Record) ([]sarama.RecordHeader, error) {
+	if c.Mode != CloudEventsBinary {
+		return []sarama.RecordHeader{
+			header("content-type", "application/cloudevents+json"),
+		}, nil
+	}
+	event, err := c.event(record)
+	if err != nil {
+		return nil, err
+	}
+	return []sarama.RecordHeader{
+		header("ce_specversion", "1.0"),
+		header("ce_id", event.id),
+		header("ce_source", event.source),
+		header("ce_type", event.eventType),
+		header("ce_time", event.time),
+		header("content-type", event.contentType),
+	}, nil
+}
+
+// event returns the attributes of the record, the id is unique per GTID and event number
+func (c *CloudEventsEncoder) event(record *Record) (*cloudEvent, error) {
+	values, err := record.Values()
+	if err != nil {
+		return nil, errors.Wrap(err, "get values failed")
+	}
+	gtid, err := GTIDFromValues(values)
+	if err != nil {
+		return nil, err
+	}
+	eventType, ok := values["event_type"].(string)
+	if !ok {
+		return nil, errors.Errorf("event_type of %s missing", record.Table)
+	}
+	timestamp, err := eventTime(values)
+	if err != nil {
+		return nil, err
+	}
+	contentType := "application/json"
+	if record.Format == "AVRO" {
+		contentType = "application/avro"
+	}
+	return &cloudEvent{
+		id:          fmt.Sprintf("%s:%d", gtid, eventNumber(values)),
+		source:      fmt.Sprintf("maxscale://%s/%s/%s", c.Host, record.Table.Database, record.Table.Name),
+		eventType:   cloudEventsTypePrefix + eventType,
+		time:        timestamp.UTC().Format(time.RFC3339),
+		contentType: contentType,
+	}, nil
+}
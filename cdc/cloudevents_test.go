@@ -0,0 +1,119 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/Shopify/sarama"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func headerMap(headers []sarama.RecordHeader) map[string]string {
+	result := make(map[string]string)
+	for _, header := range headers {
+		result[string(header.Key)] = string(header.Value)
+	}
+	return result
+}
+
+var _ = Describe("CloudEventsEncoder", func() {
+	var encoder *cdc.CloudEventsEncoder
+	data := `{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2, "timestamp": 1541348151, "event_type": "insert", "id": 4}`
+
+	BeforeEach(func() {
+		encoder = &cdc.CloudEventsEncoder{
+			Mode: cdc.CloudEventsStructured,
+			Host: "maxscale.example.com",
+		}
+	})
+
+	It("encodes structured event with record as data", func() {
+		value, err := encoder.Encode("mytopic", jsonRecord(data+"\n"))
+		Expect(err).To(BeNil())
+		Expect(string(value)).To(Equal(`{"specversion": "1.0", "id": "0-1-58:2", "source": "maxscale://maxscale.example.com/mydb/mytable", "type": "maxscale.cdc.insert", "time": "2018-11-04T16:15:51Z", "datacontenttype": "application/json", "data": ` + data + `}`))
+	})
+
+	It("returns the content type header in structured mode", func() {
+		headers, err := encoder.Headers("mytopic", jsonRecord(data))
+		Expect(err).To(BeNil())
+		Expect(headerMap(headers)).To(Equal(map[string]string{"content-type": "application/cloudevents+json"}))
+	})
+
+	It("returns error in structured mode for AVRO", func() {
+		record := jsonRecord(data)
+		record.Format = "AVRO"
+		_, err := encoder.Encode("mytopic", record)
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error without gtid", func() {
+		_, err := encoder.Encode("mytopic", jsonRecord(`{"event_type": "insert", "id": 4}`))
+		Expect(err).NotTo(BeNil())
+	})
+
+	Context("binary mode", func() {
+		BeforeEach(func() {
+			encoder.Mode = cdc.CloudEventsBinary
+		})
+
+		It("encodes the record unchanged", func() {
+			value, err := encoder.Encode("mytopic", jsonRecord(data))
+			Expect(err).To(BeNil())
+			Expect(string(value)).To(Equal(data))
+		})
+
+		It("returns the attributes as headers", func() {
+			headers, err := encoder.Headers("mytopic", jsonRecord(data))
+			Expect(err).To(BeNil())
+			Expect(headerMap(headers)).To(Equal(map[string]string{
+				"ce_specversion": "1.0",
+				"ce_id":          "0-1-58:2",
+				"ce_source":      "maxscale://maxscale.example.com/mydb/mytable",
+				"ce_type":        "maxscale.cdc.insert",
+				"ce_time":        "2018-11-04T16:15:51Z",
+				"content-type":   "application/json",
+			}))
+		})
+	})
+})
+
+var _ = Describe("KafkaSender with header encoders", func() {
+	var producer *mocks.SyncProducer
+	var sender *cdc.KafkaSender
+	var dataDir string
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = ioutil.TempDir("", "kafka-sender")
+		Expect(err).To(BeNil())
+		producer = &mocks.SyncProducer{}
+		encoder := &cdc.CloudEventsEncoder{Mode: cdc.CloudEventsBinary, Host: "localhost"}
+		sender = &cdc.KafkaSender{
+			Producer:       producer,
+			TopicRouter:    &cdc.TopicTemplate{Template: "mytopic"},
+			GTIDStore:      &cdc.GTIDStore{DataDir: dataDir},
+			ValueEncoder:   encoder,
+			HeaderEncoders: []cdc.HeaderEncoder{encoder},
+			Tombstones:     true,
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dataDir)
+	})
+
+	It("adds headers to the message but not to the tombstone", func() {
+		err := sendRecords(sender, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "timestamp": 1541348151, "event_type": "delete", "id": 4}`))
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(2))
+		Expect(headerMap(producer.SendMessageArgsForCall(0).Headers)).To(HaveKeyWithValue("ce_id", "0-1-58:1"))
+		Expect(producer.SendMessageArgsForCall(1).Headers).To(BeEmpty())
+	})
+})
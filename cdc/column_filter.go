@@ -0,0 +1,102 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"path"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	"github.com/pkg/errors"
+)
+
+// ColumnFilter removes columns from records, e.g. BLOB columns of wide tables.
+// Include and Exclude contain column names and support the wildcards of path.Match.
+// Without Include all columns are kept that are not excluded. The metadata fields of Maxscale are always kept.
+type ColumnFilter struct {
+	Include []string
+	Exclude []string
+
+	schemas map[*avro.Schema]*avro.Schema
+}
+
+// ValidateColumnPatterns returns an error if a pattern is malformed
+func ValidateColumnPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Wrapf(err, "invalid column pattern %s", pattern)
+		}
+	}
+	return nil
+}
+
+// Process removes the filtered columns from the record and its schema
+func (c *ColumnFilter) Process(record *Record) ([]*Record, error) {
+	values, err := record.Values()
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode %s record failed", record.Format)
+	}
+	removed := false
+	for name := range values {
+		if !c.keep(name) {
+			delete(values, name)
+			removed = true
+		}
+	}
+	if !removed {
+		return []*Record{record}, nil
+	}
+	result := *record
+	if record.Schema != nil {
+		if result.Schema, err = c.schema(record.Schema); err != nil {
+			return nil, errors.Wrapf(err, "filter schema of %s failed", record.Table)
+		}
+	}
+	if err := result.SetValues(values); err != nil {
+		return nil, errors.Wrapf(err, "encode %s record failed", record.Format)
+	}
+	return []*Record{&result}, nil
+}
+
+// keep returns true if the column passes the filter
+func (c *ColumnFilter) keep(name string) bool {
+	if metadataFields[name] {
+		return true
+	}
+	if len(c.Include) > 0 && !matchColumn(c.Include, name) {
+		return false
+	}
+	return !matchColumn(c.Exclude, name)
+}
+
+// schema returns the schema without the filtered fields, the result is cached per schema
+func (c *ColumnFilter) schema(schema *avro.Schema) (*avro.Schema, error) {
+	if filtered, ok := c.schemas[schema]; ok {
+		return filtered, nil
+	}
+	var fields []*avro.Field
+	for _, field := range schema.Fields {
+		if c.keep(field.Name) {
+			fields = append(fields, field)
+		}
+	}
+	filtered, err := avro.NewRecordSchema(schema.Namespace, schema.Name, fields)
+	if err != nil {
+		return nil, err
+	}
+	if c.schemas == nil {
+		c.schemas = make(map[*avro.Schema]*avro.Schema)
+	}
+	c.schemas[schema] = filtered
+	return filtered, nil
+}
+
+func matchColumn(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
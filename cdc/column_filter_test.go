@@ -0,0 +1,79 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ColumnFilter", func() {
+	values := func() map[string]interface{} {
+		return map[string]interface{}{
+			"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "timestamp": 1541348151, "event_type": "insert", "id": 4, "name": "a",
+		}
+	}
+
+	It("removes excluded columns of json records", func() {
+		filter := &cdc.ColumnFilter{Exclude: []string{"name"}}
+		records, err := filter.Process(namesRecord("JSON", values()))
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+		Expect(string(records[0].Data)).To(Equal(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "timestamp": 1541348151, "event_type": "insert", "id": 4}` + "\n"))
+	})
+
+	It("keeps only included columns and metadata fields", func() {
+		filter := &cdc.ColumnFilter{Include: []string{"i*"}}
+		records, err := filter.Process(namesRecord("JSON", values()))
+		Expect(err).To(BeNil())
+		result, err := records[0].Values()
+		Expect(err).To(BeNil())
+		Expect(result).To(HaveKey("id"))
+		Expect(result).To(HaveKey("event_type"))
+		Expect(result).NotTo(HaveKey("name"))
+	})
+
+	It("never removes metadata fields", func() {
+		filter := &cdc.ColumnFilter{Exclude: []string{"*"}}
+		records, err := filter.Process(namesRecord("JSON", values()))
+		Expect(err).To(BeNil())
+		gtid, err := records[0].GTID()
+		Expect(err).To(BeNil())
+		Expect(gtid.String()).To(Equal("0-1-58"))
+	})
+
+	It("removes columns from avro records and schema", func() {
+		filter := &cdc.ColumnFilter{Exclude: []string{"name"}}
+		record := namesRecord("AVRO", values())
+		records, err := filter.Process(record)
+		Expect(err).To(BeNil())
+		Expect(records[0].Schema.Field("name")).To(BeNil())
+		Expect(records[0].Schema.Field("id")).NotTo(BeNil())
+		result, err := records[0].Values()
+		Expect(err).To(BeNil())
+		Expect(result).NotTo(HaveKey("name"))
+		Expect(result).To(HaveKeyWithValue("id", BeEquivalentTo(4)))
+		Expect(record.Schema.Field("name")).NotTo(BeNil())
+	})
+
+	It("reuses the filtered schema", func() {
+		filter := &cdc.ColumnFilter{Exclude: []string{"name"}}
+		record := namesRecord("AVRO", values())
+		first, err := filter.Process(record)
+		Expect(err).To(BeNil())
+		second, err := filter.Process(record)
+		Expect(err).To(BeNil())
+		Expect(second[0].Schema).To(BeIdenticalTo(first[0].Schema))
+	})
+
+	It("returns the record unchanged if no column is filtered", func() {
+		filter := &cdc.ColumnFilter{Exclude: []string{"blob"}}
+		record := namesRecord("JSON", values())
+		records, err := filter.Process(record)
+		Expect(err).To(BeNil())
+		Expect(records[0]).To(BeIdenticalTo(record))
+	})
+})
@@ -0,0 +1,141 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	"github.com/pkg/errors"
+)
+
+// Envelopes of the Kafka message value
+const (
+	EnvelopeNone        = "none"
+	EnvelopeDebezium    = "debezium"
+	EnvelopeCloudEvents = "cloudevents"
+)
+
+// ValidEnvelope returns true if the given envelope is known
+func ValidEnvelope(envelope string) bool {
+	switch envelope {
+	case EnvelopeNone, EnvelopeDebezium, EnvelopeCloudEvents:
+		return true
+	default:
+		return false
+	}
+}
+
+// debeziumOps maps the Maxscale event types to the operations of Debezium
+var debeziumOps = map[interface{}]string{
+	"insert":        "c",
+	"update_before": "u",
+	"update_after":  "u",
+	"delete":        "d",
+}
+
+// DebeziumEncoder writes records in the change event envelope of Debezium,
+// {"before": ..., "after": ..., "source": ..., "op": ..., "ts_ms": ...}.
+// Updates contain both images if the UpdateMerger set the before record.
+type DebeziumEncoder struct {
+	// Now returns the processing time, default is time.Now
+	Now func() time.Time
+}
+
+// Encode the record into the envelope
+func (d *DebeziumEncoder) Encode(topic string, record *Record) ([]byte, error) {
+	values, err := record.Values()
+	if err != nil {
+		return nil, errors.Wrap(err, "get values failed")
+	}
+	op, ok := debeziumOps[values["event_type"]]
+	if !ok {
+		return nil, errors.Errorf("unknown event type %v", values["event_type"])
+	}
+	row, err := encodeRow(record.Schema, values)
+	if err != nil {
+		return nil, err
+	}
+	var before, after interface{}
+	switch values["event_type"] {
+	case "insert", "update_after":
+		after = row
+	default:
+		before = row
+	}
+	if record.Before != nil {
+		beforeValues, err := record.Before.Values()
+		if err != nil {
+			return nil, errors.Wrap(err, "get values of before image failed")
+		}
+		if before, err = encodeRow(record.Before.Schema, beforeValues); err != nil {
+			return nil, err
+		}
+	}
+	source, err := debeziumSource(record, values)
+	if err != nil {
+		return nil, err
+	}
+	return encodeJSONObject(
+		[]string{"before", "after", "source", "op", "ts_ms"},
+		map[string]interface{}{
+			"before": before,
+			"after":  after,
+			"source": source,
+			"op":     op,
+			"ts_ms":  d.now().UnixNano() / int64(time.Millisecond),
+		},
+	)
+}
+
+func (d *DebeziumEncoder) now() time.Time {
+	if d.Now == nil {
+		return time.Now()
+	}
+	return d.Now()
+}
+
+// encodeRow returns the JSON object of the table columns, without the metadata fields of Maxscale
+func encodeRow(schema *avro.Schema, values map[string]interface{}) (json.RawMessage, error) {
+	row := make(map[string]interface{})
+	for key, value := range values {
+		if !metadataFields[key] {
+			row[key] = value
+		}
+	}
+	data, err := encodeJSONObject(schemaKeys(schema, row), row)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode row failed")
+	}
+	return data, nil
+}
+
+// debeziumSource returns the source block with the position of the event in the binlog
+func debeziumSource(record *Record, values map[string]interface{}) (json.RawMessage, error) {
+	gtid, err := GTIDFromValues(values)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, _ := toUint64(values["timestamp"])
+	data, err := encodeJSONObject(
+		[]string{"connector", "db", "table", "ts_ms", "gtid", "domain", "server_id", "sequence", "event_number"},
+		map[string]interface{}{
+			"connector":    "maxscale",
+			"db":           record.Table.Database,
+			"table":        record.Table.Name,
+			"ts_ms":        timestamp * 1000,
+			"gtid":         gtid.String(),
+			"domain":       gtid.Domain,
+			"server_id":    gtid.ServerId,
+			"sequence":     gtid.Sequence,
+			"event_number": eventNumber(values),
+		},
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode source failed")
+	}
+	return data, nil
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DebeziumEncoder", func() {
+	var encoder *cdc.DebeziumEncoder
+	source := `"source": {"connector": "maxscale", "db": "mydb", "table": "mytable", "ts_ms": 1541348151000, "gtid": "0-1-58", "domain": 0, "server_id": 1, "sequence": 58, "event_number": %d}`
+
+	BeforeEach(func() {
+		encoder = &cdc.DebeziumEncoder{
+			Now: func() time.Time {
+				return time.Unix(1541348152, 0)
+			},
+		}
+	})
+
+	encode := func(record *cdc.Record) string {
+		data, err := encoder.Encode("mytopic", record)
+		Expect(err).To(BeNil())
+		return string(data)
+	}
+
+	It("encodes insert with after image", func() {
+		Expect(encode(jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "timestamp": 1541348151, "event_type": "insert", "id": 4, "name": "a"}`))).To(Equal(
+			`{"before": null, "after": {"id": 4, "name": "a"}, ` + fmt.Sprintf(source, 1) + `, "op": "c", "ts_ms": 1541348152000}`,
+		))
+	})
+
+	It("encodes delete with before image", func() {
+		Expect(encode(jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "timestamp": 1541348151, "event_type": "delete", "id": 4}`))).To(Equal(
+			`{"before": {"id": 4}, "after": null, ` + fmt.Sprintf(source, 1) + `, "op": "d", "ts_ms": 1541348152000}`,
+		))
+	})
+
+	It("encodes update with both images", func() {
+		record := jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2, "timestamp": 1541348151, "event_type": "update_after", "id": 4, "name": "b"}`)
+		record.Before = jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "timestamp": 1541348151, "event_type": "update_before", "id": 4, "name": "a"}`)
+		Expect(encode(record)).To(Equal(
+			`{"before": {"id": 4, "name": "a"}, "after": {"id": 4, "name": "b"}, ` + fmt.Sprintf(source, 2) + `, "op": "u", "ts_ms": 1541348152000}`,
+		))
+	})
+
+	It("orders columns as in the schema", func() {
+		record := namesRecord("JSON", map[string]interface{}{
+			"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "timestamp": 1541348151, "event_type": "insert", "name": "a", "id": 4,
+		})
+		Expect(encode(record)).To(HavePrefix(`{"before": null, "after": {"id": 4, "name": "a"}, "source": {"connector": "maxscale", "db": "test", "table": "names"`))
+	})
+
+	It("returns error for unknown event type", func() {
+		_, err := encoder.Encode("mytopic", jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "banana"}`))
+		Expect(err).NotTo(BeNil())
+	})
+})
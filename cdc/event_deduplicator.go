@@ -0,0 +1,41 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"github.com/golang/glog"
+)
+
+// EventDeduplicator drops events that were already forwarded.
+// Maxscale resumes with the whole transaction of the requested GTID,
+// so after a reconnect the events of the last transaction are replayed.
+type EventDeduplicator struct {
+	// GTID and EventNumber of the last forwarded event, initialized from the checkpoint
+	GTID        *GTID
+	EventNumber uint64
+}
+
+// Process drops the record if an event of the same transaction with equal or higher number was forwarded
+func (e *EventDeduplicator) Process(record *Record) ([]*Record, error) {
+	values, err := record.Values()
+	if err != nil {
+		return []*Record{record}, nil
+	}
+	gtid, err := GTIDFromValues(values)
+	if err != nil {
+		return []*Record{record}, nil
+	}
+	number := eventNumber(values)
+	if number == 0 {
+		return []*Record{record}, nil
+	}
+	if e.GTID != nil && *e.GTID == *gtid && number <= e.EventNumber {
+		glog.V(3).Infof("drop replayed event %d of %s from %s", number, record.Table, gtid)
+		return nil, nil
+	}
+	e.GTID = gtid
+	e.EventNumber = number
+	return []*Record{record}, nil
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EventDeduplicator", func() {
+	var deduplicator *cdc.EventDeduplicator
+
+	process := func(data string) []*cdc.Record {
+		records, err := deduplicator.Process(jsonRecord(data))
+		Expect(err).To(BeNil())
+		return records
+	}
+
+	BeforeEach(func() {
+		deduplicator = &cdc.EventDeduplicator{}
+	})
+
+	It("drops replayed events of the current transaction", func() {
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`)).To(HaveLen(1))
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2}`)).To(HaveLen(1))
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`)).To(BeEmpty())
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2}`)).To(BeEmpty())
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 3}`)).To(HaveLen(1))
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 59, "event_number": 1}`)).To(HaveLen(1))
+	})
+
+	It("drops events up to the event number of the checkpoint", func() {
+		deduplicator.GTID = &cdc.GTID{Domain: 0, ServerId: 1, Sequence: 58}
+		deduplicator.EventNumber = 2
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`)).To(BeEmpty())
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2}`)).To(BeEmpty())
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 3}`)).To(HaveLen(1))
+	})
+
+	It("keeps records without gtid or event number", func() {
+		deduplicator.GTID = &cdc.GTID{Domain: 0, ServerId: 1, Sequence: 58}
+		deduplicator.EventNumber = 2
+		Expect(process(`banana`)).To(HaveLen(1))
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58}`)).To(HaveLen(1))
+	})
+})
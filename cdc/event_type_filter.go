@@ -0,0 +1,49 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// eventTypes are the event types written by Maxscale
+var eventTypes = map[string]bool{
+	"insert":        true,
+	"update_before": true,
+	"update_after":  true,
+	"delete":        true,
+}
+
+// ValidateEventTypes returns an error if one of the event types is unknown
+func ValidateEventTypes(types []string) error {
+	for _, eventType := range types {
+		if !eventTypes[eventType] {
+			return errors.Errorf("unknown event type %s", eventType)
+		}
+	}
+	return nil
+}
+
+// EventTypeFilter drops records of the given event types, e.g. update_before or delete
+type EventTypeFilter struct {
+	Drop []string
+}
+
+// Process drops the record if its event type is in the drop list
+func (e *EventTypeFilter) Process(record *Record) ([]*Record, error) {
+	values, err := record.Values()
+	if err != nil {
+		return []*Record{record}, nil
+	}
+	eventType, _ := values["event_type"].(string)
+	for _, drop := range e.Drop {
+		if eventType == drop {
+			glog.V(3).Infof("drop %s record of %s", eventType, record.Table)
+			return []*Record{dropped(record)}, nil
+		}
+	}
+	return []*Record{record}, nil
+}
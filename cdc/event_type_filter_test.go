@@ -0,0 +1,49 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EventTypeFilter", func() {
+	var filter *cdc.EventTypeFilter
+
+	BeforeEach(func() {
+		filter = &cdc.EventTypeFilter{
+			Drop: []string{"update_before", "delete"},
+		}
+	})
+
+	It("drops records of the given event types", func() {
+		records, err := filter.Process(jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "delete"}`))
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Dropped).To(BeTrue())
+	})
+
+	It("keeps records of other event types", func() {
+		records, err := filter.Process(jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "update_after"}`))
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Dropped).To(BeFalse())
+	})
+
+	It("drops avro records", func() {
+		records, err := filter.Process(namesRecord("AVRO", map[string]interface{}{
+			"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "timestamp": 1, "event_type": "update_before", "id": 4, "name": "a",
+		}))
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Dropped).To(BeTrue())
+	})
+
+	It("validates event types", func() {
+		Expect(cdc.ValidateEventTypes([]string{"insert", "update_before", "update_after", "delete"})).To(BeNil())
+		Expect(cdc.ValidateEventTypes([]string{"banana"})).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,442 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// beforePrefix selects the value of the before image, e.g. before.status
+const beforePrefix = "before."
+
+// Expression is a predicate on the columns of a row, e.g. tenant_id == 42 && status != "draft".
+// It supports the comparisons == != < <= > >=, the operators && || ! and parentheses.
+// Literals are numbers, strings in double or single quotes, true, false and null.
+// Columns prefixed with before. refer to the before image of an update.
+// Missing columns are null, null equals only null. Comparing values of different types is false,
+// also for !=. < <= > >= compare numbers and strings only.
+type Expression struct {
+	source string
+	root   node
+}
+
+// ParseExpression parses the given predicate
+func ParseExpression(expression string) (*Expression, error) {
+	tokens, err := lex(expression)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse expression %s failed", expression)
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err == nil && p.pos < len(p.tokens) {
+		err = errors.Errorf("unexpected %s", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse expression %s failed", expression)
+	}
+	return &Expression{
+		source: expression,
+		root:   root,
+	}, nil
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Match returns true if the row matches. Before is the before image of an update or nil.
+func (e *Expression) Match(row map[string]interface{}, before map[string]interface{}) bool {
+	return truthy(e.root.eval(row, before))
+}
+
+type node interface {
+	eval(row map[string]interface{}, before map[string]interface{}) interface{}
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (l *literal) eval(row map[string]interface{}, before map[string]interface{}) interface{} {
+	return l.value
+}
+
+type column struct {
+	name string
+}
+
+func (c *column) eval(row map[string]interface{}, before map[string]interface{}) interface{} {
+	if strings.HasPrefix(c.name, beforePrefix) {
+		return before[strings.TrimPrefix(c.name, beforePrefix)]
+	}
+	return row[c.name]
+}
+
+type not struct {
+	operand node
+}
+
+func (n *not) eval(row map[string]interface{}, before map[string]interface{}) interface{} {
+	return !truthy(n.operand.eval(row, before))
+}
+
+type logical struct {
+	op          string
+	left, right node
+}
+
+func (l *logical) eval(row map[string]interface{}, before map[string]interface{}) interface{} {
+	left := truthy(l.left.eval(row, before))
+	if l.op == "&&" {
+		return left && truthy(l.right.eval(row, before))
+	}
+	return left || truthy(l.right.eval(row, before))
+}
+
+type comparison struct {
+	op          string
+	left, right node
+}
+
+func (c *comparison) eval(row map[string]interface{}, before map[string]interface{}) interface{} {
+	left := c.left.eval(row, before)
+	result, ok := compare(left, c.right.eval(row, before))
+	switch c.op {
+	case "==":
+		return ok && result == 0
+	case "!=":
+		return ok && result != 0
+	}
+	ok = ok && ordered(left)
+	switch c.op {
+	case "<":
+		return ok && result < 0
+	case "<=":
+		return ok && result <= 0
+	case ">":
+		return ok && result > 0
+	default:
+		return ok && result >= 0
+	}
+}
+
+// compare returns -1, 0 or 1 and false if the values are not comparable
+func compare(left, right interface{}) (int, bool) {
+	if left == nil || right == nil {
+		if left == nil && right == nil {
+			return 0, true
+		}
+		return 1, true
+	}
+	if l, ok := number(left); ok {
+		r, ok := number(right)
+		if !ok {
+			return 0, false
+		}
+		return compareNumbers(l, r), true
+	}
+	if l, ok := left.(bool); ok {
+		r, ok := right.(bool)
+		if !ok {
+			return 0, false
+		}
+		if l != r {
+			return 1, true
+		}
+		return 0, true
+	}
+	l, ok := text(left)
+	if !ok {
+		return 0, false
+	}
+	r, ok := text(right)
+	if !ok {
+		return 0, false
+	}
+	return strings.Compare(l, r), true
+}
+
+// ordered returns true for values with an order, numbers and strings
+func ordered(value interface{}) bool {
+	switch value.(type) {
+	case nil, bool:
+		return false
+	default:
+		return true
+	}
+}
+
+func compareNumbers(left, right json.Number) int {
+	if l, err := left.Int64(); err == nil {
+		if r, err := right.Int64(); err == nil {
+			switch {
+			case l < r:
+				return -1
+			case l > r:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+	l, _ := left.Float64()
+	r, _ := right.Float64()
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func number(value interface{}) (json.Number, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		return v, true
+	case int:
+		return json.Number(strconv.Itoa(v)), true
+	case int32:
+		return json.Number(strconv.FormatInt(int64(v), 10)), true
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10)), true
+	case float32:
+		return json.Number(strconv.FormatFloat(float64(v), 'g', -1, 32)), true
+	case float64:
+		return json.Number(strconv.FormatFloat(v, 'g', -1, 64)), true
+	default:
+		return "", false
+	}
+}
+
+func text(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	default:
+		return "", false
+	}
+}
+
+// truthy returns true for true, numbers other than 0 and non empty strings
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case nil:
+		return false
+	}
+	if n, ok := number(value); ok {
+		f, err := n.Float64()
+		return err == nil && f != 0
+	}
+	s, ok := text(value)
+	return ok && s != ""
+}
+
+// token types of the lexer
+const (
+	tokenColumn = iota
+	tokenLiteral
+	tokenOperator
+)
+
+type token struct {
+	kind  int
+	text  string
+	value interface{}
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")"}
+
+func lex(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			var value strings.Builder
+			for ; end < len(runes) && runes[end] != r; end++ {
+				if runes[end] == '\\' && end+1 < len(runes) {
+					end++
+				}
+				value.WriteRune(runes[end])
+			}
+			if end >= len(runes) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokenLiteral, text: string(runes[i : end+1]), value: value.String()})
+			i = end + 1
+		case unicode.IsDigit(r) || r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			end := i + 1
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			text := string(runes[i:end])
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, errors.Errorf("invalid number %s", text)
+			}
+			tokens = append(tokens, token{kind: tokenLiteral, text: text, value: json.Number(text)})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '.') {
+				end++
+			}
+			text := string(runes[i:end])
+			switch text {
+			case "true":
+				tokens = append(tokens, token{kind: tokenLiteral, text: text, value: true})
+			case "false":
+				tokens = append(tokens, token{kind: tokenLiteral, text: text, value: false})
+			case "null":
+				tokens = append(tokens, token{kind: tokenLiteral, text: text})
+			default:
+				tokens = append(tokens, token{kind: tokenColumn, text: text})
+			}
+			i = end
+		default:
+			operator := ""
+			for _, op := range operators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					operator = op
+					break
+				}
+			}
+			if operator == "" {
+				return nil, errors.Errorf("unexpected character %c", r)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: operator})
+			i += len(operator)
+		}
+	}
+	return tokens, nil
+}
+
+// parser is a recursive descent parser, precedence from low to high is || && ! and comparisons
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek(texts ...string) string {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOperator {
+		return ""
+	}
+	for _, text := range texts {
+		if p.tokens[p.pos].text == text {
+			return text
+		}
+	}
+	return ""
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("||") != "" {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("&&") != "" {
+		p.pos++
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (node, error) {
+	if p.peek("!") != "" {
+		p.pos++
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &not{operand: operand}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if op := p.peek("==", "!=", "<=", ">=", "<", ">"); op != "" {
+		p.pos++
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if op != "==" && op != "!=" && (!orderedLiteral(left) || !orderedLiteral(right)) {
+			return nil, errors.Errorf("%s requires numbers or strings", op)
+		}
+		return &comparison{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+// orderedLiteral returns false for literals without an order, true, false and null
+func orderedLiteral(n node) bool {
+	l, ok := n.(*literal)
+	return !ok || ordered(l.value)
+}
+
+func (p *parser) operand() (node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("unexpected end")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch {
+	case t.kind == tokenLiteral:
+		return &literal{value: t.value}, nil
+	case t.kind == tokenColumn:
+		return &column{name: t.text}, nil
+	case t.text == "(":
+		result, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek(")") == "" {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return result, nil
+	default:
+		return nil, errors.Errorf("unexpected %s", t.text)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"encoding/json"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Expression", func() {
+	row := map[string]interface{}{
+		"tenant_id": json.Number("42"),
+		"status":    "open",
+		"total":     float64(9.5),
+		"count":     int32(3),
+		"deleted":   false,
+		"note":      nil,
+		"name":      []byte("O'Brien"),
+	}
+	before := map[string]interface{}{
+		"status": "draft",
+	}
+
+	for expression, expected := range map[string]bool{
+		`tenant_id == 42`:                                   true,
+		`tenant_id == 43`:                                   false,
+		`tenant_id != 43`:                                   true,
+		`tenant_id == 42 && status != "draft"`:              true,
+		`tenant_id == 42 && status == 'draft'`:              false,
+		`tenant_id == 1 || status == "open"`:                true,
+		`!(tenant_id == 42)`:                                false,
+		`total > 9 && total <= 9.5`:                         true,
+		`count >= 3 && count < 4`:                           true,
+		`count > -1`:                                        true,
+		`deleted == false`:                                  true,
+		`!deleted`:                                          true,
+		`note == null`:                                      true,
+		`note != null`:                                      false,
+		`missing == null`:                                   true,
+		`tenant_id == "42"`:                                 false,
+		`tenant_id != "42"`:                                 false,
+		`name == "O\'Brien"`:                                true,
+		`status > "a"`:                                      true,
+		`before.status == "draft"`:                          true,
+		`before.status != status`:                           true,
+		`tenant_id == 1 || tenant_id == 2 && count == 3`:    false,
+		`(tenant_id == 1 || tenant_id == 42) && count == 3`: true,
+		`deleted != 0 || tenant_id != "42" || status != 1`:  false,
+		`deleted != true && status != null && missing != 1`: true,
+	} {
+		expression, expected := expression, expected
+		It("evaluates "+expression, func() {
+			e, err := cdc.ParseExpression(expression)
+			Expect(err).To(BeNil())
+			Expect(e.Match(row, before)).To(Equal(expected))
+		})
+	}
+
+	for _, expression := range []string{
+		``,
+		`tenant_id ==`,
+		`tenant_id == 42 &&`,
+		`(tenant_id == 42`,
+		`tenant_id == 42)`,
+		`status == "open`,
+		`tenant_id = 42`,
+		`tenant_id == 4.2.1`,
+		`deleted < true`,
+		`false >= deleted`,
+		`count > null`,
+	} {
+		expression := expression
+		It("returns error for "+expression, func() {
+			_, err := cdc.ParseExpression(expression)
+			Expect(err).NotTo(BeNil())
+		})
+	}
+})
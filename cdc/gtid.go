@@ -55,3 +55,86 @@ func ParseGTID(gtid string) (*GTID, error) {
 		Sequence: sequence,
 	}, nil
 }
+
+// GTIDPosition is the latest GTID of each domain, like gtid_slave_pos.
+// GTIDs are ordered by the time they were updated, the last one is the position to resume from.
+type GTIDPosition []GTID
+
+// ParseGTIDPosition returns the position of the given comma separated GTIDs, e.g. 0-1-58,1-2-10.
+// For empty string it returns an empty position.
+func ParseGTIDPosition(position string) (GTIDPosition, error) {
+	var result GTIDPosition
+	for _, part := range strings.Split(position, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		gtid, err := ParseGTID(part)
+		if err != nil {
+			return nil, err
+		}
+		if result.Domain(gtid.Domain) != nil {
+			return nil, errors.Errorf("duplicate domain %d in gtid position %s", gtid.Domain, position)
+		}
+		result = append(result, *gtid)
+	}
+	return result, nil
+}
+
+// String returns the comma separated GTIDs of the position
+func (p GTIDPosition) String() string {
+	parts := make([]string, len(p))
+	for i, gtid := range p {
+		parts[i] = gtid.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Domain returns the GTID of the given domain or nil if the domain is unknown
+func (p GTIDPosition) Domain(domain uint32) *GTID {
+	for i := range p {
+		if p[i].Domain == domain {
+			return &p[i]
+		}
+	}
+	return nil
+}
+
+// Last returns the GTID updated last or nil for an empty position
+func (p GTIDPosition) Last() *GTID {
+	if len(p) == 0 {
+		return nil
+	}
+	return &p[len(p)-1]
+}
+
+// Update returns a new position with the given GTID as latest of its domain.
+// GTIDs older than the known GTID of the domain are ignored.
+func (p GTIDPosition) Update(gtid *GTID) GTIDPosition {
+	if known := p.Domain(gtid.Domain); known != nil && known.Sequence > gtid.Sequence {
+		return p
+	}
+	result := make(GTIDPosition, 0, len(p)+1)
+	for _, g := range p {
+		if g.Domain != gtid.Domain {
+			result = append(result, g)
+		}
+	}
+	return append(result, *gtid)
+}
+
+// Passed returns true if the position already contains a later transaction of the GTID's domain
+func (p GTIDPosition) Passed(gtid *GTID) bool {
+	known := p.Domain(gtid.Domain)
+	return known != nil && known.Sequence > gtid.Sequence
+}
+
+// Ahead returns true if a domain of the position has a later transaction than the same domain of the other position.
+// Domains missing in one of the positions are not compared.
+func (p GTIDPosition) Ahead(other GTIDPosition) bool {
+	for _, gtid := range p {
+		if o := other.Domain(gtid.Domain); o != nil && o.Sequence < gtid.Sequence {
+			return true
+		}
+	}
+	return false
+}
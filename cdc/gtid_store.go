@@ -5,31 +5,101 @@
 package cdc
 
 import (
+	"fmt"
+	"hash/crc32"
 	"io/ioutil"
+	"os"
 	"path"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 )
 
-// GTIDStore save GTID to disk
+// GTIDStore save the checkpoint to disk
 type GTIDStore struct {
 	DataDir string
+	Table   Table
+	// Legacy reads the lastgtid file of versions with a single table if the table has no file yet
+	Legacy bool
 }
 
-// Read the last GTID from disk
-func (g *GTIDStore) Read() (*GTID, error) {
-	content, err := ioutil.ReadFile(g.path())
+// Read the checkpoint from disk. The checksum is verified if the file contains one.
+func (g *GTIDStore) Read() (*Checkpoint, error) {
+	checkpoint, err := readCheckpointFile(g.path())
+	if g.Legacy && g.Table != (Table{}) && os.IsNotExist(errors.Cause(err)) {
+		return readCheckpointFile(path.Join(g.DataDir, "lastgtid"))
+	}
+	return checkpoint, err
+}
+
+func readCheckpointFile(filename string) (*Checkpoint, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read file %s failed", filename)
+	}
+	// files of older versions contain only the GTID, a file with separator must have a valid checksum
+	if legacy := strings.TrimSuffix(string(content), "\n"); !strings.ContainsAny(legacy, " \t\n") {
+		return ParseCheckpoint(legacy)
+	}
+	parts := strings.Fields(string(content))
+	if len(parts) != 2 {
+		return nil, errors.Errorf("parse file %s failed", filename)
+	}
+	checksum, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse checksum of %s failed", filename)
+	}
+	if crc32.ChecksumIEEE([]byte(parts[0])) != uint32(checksum) {
+		return nil, errors.Errorf("checksum of %s invalid", filename)
+	}
+	return ParseCheckpoint(parts[0])
+}
+
+// Write the given checkpoint with checksum to disk.
+// The file is replaced atomically, a crash never leaves a truncated file.
+func (g *GTIDStore) Write(checkpoint *Checkpoint) error {
+	value := checkpoint.String()
+	content := fmt.Sprintf("%s %08x\n", value, crc32.ChecksumIEEE([]byte(value)))
+	file, err := ioutil.TempFile(g.DataDir, ".lastgtid-")
 	if err != nil {
-		return nil, errors.Wrapf(err, "read file %s failed", g.path())
+		return errors.Wrap(err, "create temp file failed")
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return errors.Wrapf(err, "write file %s failed", file.Name())
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return errors.Wrapf(err, "sync file %s failed", file.Name())
+	}
+	if err := file.Close(); err != nil {
+		return errors.Wrapf(err, "close file %s failed", file.Name())
 	}
-	return ParseGTID(string(content))
+	if err := os.Rename(file.Name(), g.path()); err != nil {
+		return errors.Wrapf(err, "rename %s to %s failed", file.Name(), g.path())
+	}
+	return syncDir(g.DataDir)
 }
 
-// Write the given GTID to disk
-func (g *GTIDStore) Write(gtid *GTID) error {
-	return ioutil.WriteFile(g.path(), []byte(gtid.String()), 0600)
+// syncDir persists the rename of a file in the directory
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrapf(err, "open dir %s failed", dir)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return errors.Wrapf(err, "sync dir %s failed", dir)
+	}
+	return nil
 }
 
+// path of the gtid file, each table has its own file
 func (g *GTIDStore) path() string {
-	return path.Join(g.DataDir, "lastgtid")
+	if g.Table == (Table{}) {
+		return path.Join(g.DataDir, "lastgtid")
+	}
+	return path.Join(g.DataDir, fmt.Sprintf("lastgtid-%s", g.Table))
 }
@@ -0,0 +1,139 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("GTIDStore", func() {
+	var dataDir string
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = ioutil.TempDir("", "gtid-store")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dataDir)
+	})
+
+	It("returns written checkpoint", func() {
+		store := &cdc.GTIDStore{DataDir: dataDir}
+		checkpoint, err := cdc.ParseCheckpoint("1-2-3,0-1-58#7")
+		Expect(err).To(BeNil())
+		Expect(store.Write(checkpoint)).To(BeNil())
+		result, err := store.Read()
+		Expect(err).To(BeNil())
+		Expect(result).To(Equal(checkpoint))
+	})
+
+	It("returns error if nothing was written", func() {
+		store := &cdc.GTIDStore{DataDir: dataDir}
+		_, err := store.Read()
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("stores gtid per table", func() {
+		storeA := &cdc.GTIDStore{DataDir: dataDir, Table: cdc.Table{Database: "mydb", Name: "a"}}
+		storeB := &cdc.GTIDStore{DataDir: dataDir, Table: cdc.Table{Database: "mydb", Name: "b"}}
+		gtidA, err := cdc.ParseCheckpoint("0-1-10")
+		Expect(err).To(BeNil())
+		gtidB, err := cdc.ParseCheckpoint("0-1-20")
+		Expect(err).To(BeNil())
+		Expect(storeA.Write(gtidA)).To(BeNil())
+		Expect(storeB.Write(gtidB)).To(BeNil())
+		result, err := storeA.Read()
+		Expect(err).To(BeNil())
+		Expect(result).To(Equal(gtidA))
+		result, err = storeB.Read()
+		Expect(err).To(BeNil())
+		Expect(result).To(Equal(gtidB))
+	})
+
+	It("reads legacy gtid file if table has no file yet", func() {
+		Expect(ioutil.WriteFile(path.Join(dataDir, "lastgtid"), []byte("0-1-58\n"), 0644)).To(BeNil())
+		store := &cdc.GTIDStore{DataDir: dataDir, Table: cdc.Table{Database: "mydb", Name: "a"}, Legacy: true}
+		result, err := store.Read()
+		Expect(err).To(BeNil())
+		Expect(result.String()).To(Equal("0-1-58"))
+		checkpoint, err := cdc.ParseCheckpoint("0-1-60")
+		Expect(err).To(BeNil())
+		Expect(store.Write(checkpoint)).To(BeNil())
+		result, err = store.Read()
+		Expect(err).To(BeNil())
+		Expect(result).To(Equal(checkpoint))
+	})
+
+	It("ignores legacy gtid file of other tables", func() {
+		Expect(ioutil.WriteFile(path.Join(dataDir, "lastgtid"), []byte("0-1-58\n"), 0644)).To(BeNil())
+		store := &cdc.GTIDStore{DataDir: dataDir, Table: cdc.Table{Database: "mydb", Name: "b"}}
+		_, err := store.Read()
+		Expect(os.IsNotExist(errors.Cause(err))).To(BeTrue())
+	})
+})
+
+var _ = Describe("GTIDStore file", func() {
+	var dataDir string
+	var store *cdc.GTIDStore
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = ioutil.TempDir("", "gtid-store")
+		Expect(err).To(BeNil())
+		store = &cdc.GTIDStore{DataDir: dataDir}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dataDir)
+	})
+
+	It("writes gtid with checksum and leaves no temp files", func() {
+		Expect(store.Write(&cdc.Checkpoint{Position: cdc.GTIDPosition{{Domain: 0, ServerId: 1, Sequence: 58}}})).To(BeNil())
+		files, err := ioutil.ReadDir(dataDir)
+		Expect(err).To(BeNil())
+		Expect(files).To(HaveLen(1))
+		content, err := ioutil.ReadFile(path.Join(dataDir, "lastgtid"))
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(MatchRegexp(`^0-1-58 [0-9a-f]{8}\n$`))
+	})
+
+	It("reads file without checksum", func() {
+		Expect(ioutil.WriteFile(path.Join(dataDir, "lastgtid"), []byte("0-1-58"), 0600)).To(BeNil())
+		gtid, err := store.Read()
+		Expect(err).To(BeNil())
+		Expect(gtid.String()).To(Equal("0-1-58"))
+	})
+
+	It("returns error if checksum does not match", func() {
+		Expect(ioutil.WriteFile(path.Join(dataDir, "lastgtid"), []byte("0-1-58 00000000\n"), 0600)).To(BeNil())
+		_, err := store.Read()
+		Expect(err).NotTo(BeNil())
+	})
+
+	for _, content := range []string{"0-1-58 ", "0-1-58 \n", "0-1-58 5a3b", "0-1-58 00000000 00000000\n"} {
+		content := content
+		It(fmt.Sprintf("returns error for checksum missing in %q", content), func() {
+			Expect(ioutil.WriteFile(path.Join(dataDir, "lastgtid"), []byte(content), 0600)).To(BeNil())
+			_, err := store.Read()
+			Expect(err).NotTo(BeNil())
+		})
+	}
+
+	It("returns error for truncated file", func() {
+		Expect(ioutil.WriteFile(path.Join(dataDir, "lastgtid"), []byte("0-1-"), 0600)).To(BeNil())
+		_, err := store.Read()
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -35,3 +35,81 @@ var _ = Describe("GTID", func() {
 	})
 
 })
+
+var _ = Describe("GTIDPosition", func() {
+
+	It("parses comma separated gtids", func() {
+		position, err := cdc.ParseGTIDPosition("0-1-58, 1-2-10")
+		Expect(err).To(BeNil())
+		Expect(position).To(HaveLen(2))
+		Expect(position.Domain(0).String()).To(Equal("0-1-58"))
+		Expect(position.Domain(1).String()).To(Equal("1-2-10"))
+		Expect(position.Domain(2)).To(BeNil())
+		Expect(position.Last().String()).To(Equal("1-2-10"))
+		Expect(position.String()).To(Equal("0-1-58,1-2-10"))
+	})
+
+	It("parses single gtid", func() {
+		position, err := cdc.ParseGTIDPosition("0-1-58")
+		Expect(err).To(BeNil())
+		Expect(position.String()).To(Equal("0-1-58"))
+	})
+
+	It("returns empty position for empty string", func() {
+		position, err := cdc.ParseGTIDPosition("")
+		Expect(err).To(BeNil())
+		Expect(position).To(BeEmpty())
+		Expect(position.Last()).To(BeNil())
+		Expect(position.String()).To(Equal(""))
+	})
+
+	It("returns error for duplicate domain", func() {
+		_, err := cdc.ParseGTIDPosition("0-1-58,0-1-59")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error for invalid gtid", func() {
+		_, err := cdc.ParseGTIDPosition("0-1-58,banana")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("updates the gtid of the domain and moves it to the end", func() {
+		position, err := cdc.ParseGTIDPosition("0-1-58,1-2-10")
+		Expect(err).To(BeNil())
+		updated := position.Update(&cdc.GTID{Domain: 0, ServerId: 1, Sequence: 59})
+		Expect(updated.String()).To(Equal("1-2-10,0-1-59"))
+		Expect(position.String()).To(Equal("0-1-58,1-2-10"))
+		updated = updated.Update(&cdc.GTID{Domain: 2, ServerId: 3, Sequence: 1})
+		Expect(updated.String()).To(Equal("1-2-10,0-1-59,2-3-1"))
+	})
+
+	It("ignores older gtids on update", func() {
+		position, err := cdc.ParseGTIDPosition("0-1-58")
+		Expect(err).To(BeNil())
+		Expect(position.Update(&cdc.GTID{Domain: 0, ServerId: 1, Sequence: 57}).String()).To(Equal("0-1-58"))
+	})
+
+	It("returns passed for older transactions of the domain", func() {
+		position, err := cdc.ParseGTIDPosition("0-1-58,1-2-10")
+		Expect(err).To(BeNil())
+		Expect(position.Passed(&cdc.GTID{Domain: 0, ServerId: 1, Sequence: 57})).To(BeTrue())
+		Expect(position.Passed(&cdc.GTID{Domain: 0, ServerId: 1, Sequence: 58})).To(BeFalse())
+		Expect(position.Passed(&cdc.GTID{Domain: 1, ServerId: 2, Sequence: 11})).To(BeFalse())
+		Expect(position.Passed(&cdc.GTID{Domain: 2, ServerId: 2, Sequence: 1})).To(BeFalse())
+	})
+
+	It("returns ahead if a domain has a later transaction", func() {
+		a, err := cdc.ParseGTIDPosition("0-1-58,1-2-10")
+		Expect(err).To(BeNil())
+		b, err := cdc.ParseGTIDPosition("1-2-10,0-1-59")
+		Expect(err).To(BeNil())
+		c, err := cdc.ParseGTIDPosition("1-2-9")
+		Expect(err).To(BeNil())
+		Expect(a.Ahead(a)).To(BeFalse())
+		Expect(a.Ahead(b)).To(BeFalse())
+		Expect(b.Ahead(a)).To(BeTrue())
+		Expect(a.Ahead(c)).To(BeTrue())
+		Expect(c.Ahead(a)).To(BeFalse())
+		Expect(a.Ahead(nil)).To(BeFalse())
+	})
+})
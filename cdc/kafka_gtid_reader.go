@@ -0,0 +1,193 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// GTID recovery modes
+const (
+	// GTIDRecoveryNone uses only the GTID stored in the DataDir
+	GTIDRecoveryNone = "none"
+	// GTIDRecoveryFallback reads the GTID from Kafka if none is stored in the DataDir
+	GTIDRecoveryFallback = "fallback"
+	// GTIDRecoveryKafka always reads the GTID from Kafka
+	GTIDRecoveryKafka = "kafka"
+	// GTIDRecoveryCheck fails if the stored GTID differs from the GTID in Kafka
+	GTIDRecoveryCheck = "check"
+)
+
+// ValidGTIDRecovery returns true if the given recovery mode is known
+func ValidGTIDRecovery(mode string) bool {
+	switch mode {
+	case GTIDRecoveryNone, GTIDRecoveryFallback, GTIDRecoveryKafka, GTIDRecoveryCheck:
+		return true
+	default:
+		return false
+	}
+}
+
+// lastMessages is the number of messages read from the end of each partition,
+// tombstones and other messages without GTID are skipped.
+const lastMessages = 10
+
+// NewClient returns a Kafka client for the given comma separated brokers
+func NewClient(kafkaBrokers string) (sarama.Client, error) {
+	client, err := sarama.NewClient(strings.Split(kafkaBrokers, ","), newSaramaConfig())
+	if err != nil {
+		return nil, errors.Wrap(err, "create client failed")
+	}
+	return client, nil
+}
+
+// KafkaGTIDReader finds the GTID position of the last messages written to a topic.
+// The GTID is taken from the JSON value or from the key if messages are keyed by GTID.
+type KafkaGTIDReader struct {
+	Client  sarama.Client
+	Timeout time.Duration
+}
+
+// LastPosition returns the position of the last messages of all partitions.
+// The GTID of each domain is the one with the highest sequence. Sequences of different domains are not comparable,
+// the domain of the latest message timestamp is the last of the position.
+// It returns an empty position if the topic does not exist or contains no messages with GTID.
+func (k *KafkaGTIDReader) LastPosition(topic string) (GTIDPosition, error) {
+	partitions, err := k.Client.Partitions(topic)
+	if err == sarama.ErrUnknownTopicOrPartition {
+		glog.V(1).Infof("topic %s not found", topic)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "get partitions of %s failed", topic)
+	}
+	consumer, err := sarama.NewConsumerFromClient(k.Client)
+	if err != nil {
+		return nil, errors.Wrap(err, "create consumer failed")
+	}
+	defer consumer.Close()
+	var messages []*sarama.ConsumerMessage
+	for _, partition := range partitions {
+		partitionMessages, err := k.lastMessages(consumer, topic, partition)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, partitionMessages...)
+	}
+	var result GTIDPosition
+	timestamps := make(map[uint32]time.Time)
+	for _, msg := range messages {
+		gtid := gtidOfMessage(msg)
+		if gtid == nil {
+			continue
+		}
+		if known := result.Domain(gtid.Domain); known != nil && known.Sequence >= gtid.Sequence {
+			continue
+		}
+		result = result.Update(gtid)
+		timestamps[gtid.Domain] = msg.Timestamp
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return timestamps[result[i].Domain].Before(timestamps[result[j].Domain])
+	})
+	return result, nil
+}
+
+func (k *KafkaGTIDReader) lastMessages(consumer sarama.Consumer, topic string, partition int32) ([]*sarama.ConsumerMessage, error) {
+	return readMessages(k.Client, consumer, topic, partition, lastMessages, k.timeout())
+}
+
+// endOfPartitionInterval is the interval to check if the partition consumer reached the high water mark
+// and receives no further messages, e.g. if the last offsets were removed by compaction
+const endOfPartitionInterval = 100 * time.Millisecond
+
+// readMessages returns the given number of messages from the end of the partition, all messages for count 0
+func readMessages(client sarama.Client, consumer sarama.Consumer, topic string, partition int32, count int64, timeout time.Duration) ([]*sarama.ConsumerMessage, error) {
+	newest, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get newest offset of %s/%d failed", topic, partition)
+	}
+	oldest, err := client.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get oldest offset of %s/%d failed", topic, partition)
+	}
+	if newest <= oldest {
+		return nil, nil
+	}
+	offset := oldest
+	if count > 0 && newest-count > oldest {
+		offset = newest - count
+	}
+	partitionConsumer, err := consumer.ConsumePartition(topic, partition, offset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "consume %s/%d failed", topic, partition)
+	}
+	defer partitionConsumer.Close()
+	var result []*sarama.ConsumerMessage
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(endOfPartitionInterval)
+	defer ticker.Stop()
+	idle := 0
+	for {
+		select {
+		case msg := <-partitionConsumer.Messages():
+			result = append(result, msg)
+			idle = 0
+			if msg.Offset >= newest-1 {
+				glog.V(2).Infof("read %d messages of %s/%d", len(result), topic, partition)
+				return result, nil
+			}
+		case <-ticker.C:
+			if partitionConsumer.HighWaterMarkOffset() < newest {
+				continue
+			}
+			// no message for two intervals after the fetch reached the high water mark
+			if idle++; idle >= 2 {
+				glog.V(2).Infof("read %d messages of %s/%d until end of partition", len(result), topic, partition)
+				return result, nil
+			}
+		case <-deadline:
+			return nil, errors.Errorf("read messages of %s/%d timed out", topic, partition)
+		}
+	}
+}
+
+func (k *KafkaGTIDReader) timeout() time.Duration {
+	if k.Timeout <= 0 {
+		return 30 * time.Second
+	}
+	return k.Timeout
+}
+
+func gtidOfMessage(msg *sarama.ConsumerMessage) *GTID {
+	record := &Record{
+		Format: "JSON",
+		Data:   msg.Value,
+	}
+	values, err := record.Values()
+	if err == nil {
+		if gtid, err := GTIDFromValues(values); err == nil {
+			return gtid
+		}
+		// source of the Debezium envelope or data of a structured CloudEvent
+		for _, field := range []string{"source", "data"} {
+			if nested, ok := values[field].(map[string]interface{}); ok {
+				if gtid, err := GTIDFromValues(nested); err == nil {
+					return gtid
+				}
+			}
+		}
+	}
+	if gtid, err := ParseGTID(string(msg.Key)); err == nil {
+		return gtid
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KafkaGTIDReader", func() {
+	var broker *sarama.MockBroker
+	var client sarama.Client
+	var reader *cdc.KafkaGTIDReader
+
+	BeforeEach(func() {
+		broker = sarama.NewMockBroker(GinkgoT(), 1)
+		broker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest": sarama.NewMockMetadataResponse(GinkgoT()).
+				SetBroker(broker.Addr(), broker.BrokerID()).
+				SetLeader("mytopic", 0, broker.BrokerID()).
+				SetLeader("mytopic", 1, broker.BrokerID()).
+				SetLeader("empty", 0, broker.BrokerID()),
+			"OffsetRequest": sarama.NewMockOffsetResponse(GinkgoT()).
+				SetOffset("mytopic", 0, sarama.OffsetOldest, 0).
+				SetOffset("mytopic", 0, sarama.OffsetNewest, 2).
+				SetOffset("mytopic", 1, sarama.OffsetOldest, 0).
+				SetOffset("mytopic", 1, sarama.OffsetNewest, 2).
+				SetOffset("empty", 0, sarama.OffsetOldest, 0).
+				SetOffset("empty", 0, sarama.OffsetNewest, 0),
+			"FetchRequest": sarama.NewMockFetchResponse(GinkgoT(), 10).
+				SetMessage("mytopic", 0, 0, sarama.StringEncoder(`{"domain": 0, "server_id": 1, "sequence": 58}`)).
+				SetMessage("mytopic", 0, 1, sarama.StringEncoder(`{"domain": 0, "server_id": 1, "sequence": 60}`)).
+				SetMessage("mytopic", 1, 0, sarama.StringEncoder(`{"domain": 0, "server_id": 1, "sequence": 59}`)).
+				SetMessage("mytopic", 1, 1, sarama.StringEncoder(`{"domain": 1, "server_id": 2, "sequence": 5}`)).
+				SetHighWaterMark("mytopic", 0, 2).
+				SetHighWaterMark("mytopic", 1, 2),
+		})
+		var err error
+		client, err = sarama.NewClient([]string{broker.Addr()}, sarama.NewConfig())
+		Expect(err).To(BeNil())
+		reader = &cdc.KafkaGTIDReader{
+			Client:  client,
+			Timeout: 5 * time.Second,
+		}
+	})
+
+	AfterEach(func() {
+		_ = client.Close()
+		broker.Close()
+	})
+
+	It("returns the highest gtid per domain of all partitions", func() {
+		position, err := reader.LastPosition("mytopic")
+		Expect(err).To(BeNil())
+		Expect(position).To(HaveLen(2))
+		Expect(position.Domain(0).String()).To(Equal("0-1-60"))
+		Expect(position.Domain(1).String()).To(Equal("1-2-5"))
+	})
+
+	It("stops at the end of the partition if the last offsets are compacted", func() {
+		fetchResponse := &sarama.FetchResponse{}
+		fetchResponse.AddMessage("compacted", 0, nil, sarama.StringEncoder(`{"domain": 0, "server_id": 1, "sequence": 58}`), 5)
+		fetchResponse.GetBlock("compacted", 0).HighWaterMarkOffset = 10
+		compacted := sarama.NewMockBroker(GinkgoT(), 2)
+		defer compacted.Close()
+		compacted.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest": sarama.NewMockMetadataResponse(GinkgoT()).
+				SetBroker(compacted.Addr(), compacted.BrokerID()).
+				SetLeader("compacted", 0, compacted.BrokerID()),
+			"OffsetRequest": sarama.NewMockOffsetResponse(GinkgoT()).
+				SetOffset("compacted", 0, sarama.OffsetOldest, 0).
+				SetOffset("compacted", 0, sarama.OffsetNewest, 10),
+			"FetchRequest": sarama.NewMockWrapper(fetchResponse),
+		})
+		compactedClient, err := sarama.NewClient([]string{compacted.Addr()}, sarama.NewConfig())
+		Expect(err).To(BeNil())
+		defer compactedClient.Close()
+		reader.Client = compactedClient
+
+		start := time.Now()
+		position, err := reader.LastPosition("compacted")
+		Expect(err).To(BeNil())
+		Expect(position.String()).To(Equal("0-1-58"))
+		Expect(time.Since(start)).To(BeNumerically("<", reader.Timeout))
+	})
+
+	It("returns empty position for empty topic", func() {
+		position, err := reader.LastPosition("empty")
+		Expect(err).To(BeNil())
+		Expect(position).To(BeEmpty())
+	})
+})
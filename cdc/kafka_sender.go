@@ -7,75 +7,373 @@ package cdc
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
 )
 
-// KafkaSender takes a channel of []byte and send them to the given topic
-type KafkaSender struct {
-	KafkaBrokers string
-	KafkaTopic   string
-	GTIDStore    interface {
-		Write(gtid *GTID) error
-	}
-	GTIDExtractor interface {
-		Parse(line []byte) (*GTID, error)
-	}
-}
-
-// Send the given messages to a topic in Kafka
-func (k *KafkaSender) Send(ctx context.Context, ch <-chan []byte) error {
+func newSaramaConfig() *sarama.Config {
 	config := sarama.NewConfig()
 	config.Version = sarama.V2_0_0_0
 	config.Producer.RequiredAcks = sarama.WaitForAll
 	config.Producer.Retry.Max = 10
 	config.Producer.Return.Successes = true
+	return config
+}
 
-	glog.V(3).Infof("connect to brokers %s", k.KafkaBrokers)
+// NewSyncProducer returns a producer for the given comma separated brokers.
+// The producer is safe for concurrent use and can be shared by multiple KafkaSender.
+func NewSyncProducer(kafkaBrokers string) (SyncProducer, error) {
+	glog.V(3).Infof("connect to brokers %s", kafkaBrokers)
 
-	client, err := sarama.NewClient(strings.Split(k.KafkaBrokers, ","), config)
+	producer, err := sarama.NewSyncProducer(strings.Split(kafkaBrokers, ","), newSaramaConfig())
 	if err != nil {
-		return errors.Wrap(err, "create client failed")
+		return nil, errors.Wrap(err, "create sync producer failed")
 	}
-	defer client.Close()
+	return producer, nil
+}
 
-	producer, err := sarama.NewSyncProducerFromClient(client)
-	if err != nil {
-		return errors.Wrap(err, "create sync producer failed")
+// SyncProducer for send messages to Kafka
+//
+//go:generate counterfeiter -o ../mocks/sync_producer.go --fake-name SyncProducer . SyncProducer
+type SyncProducer interface {
+	sarama.SyncProducer
+}
+
+// ValueEncoder converts a record into the value of a Kafka message
+type ValueEncoder interface {
+	Encode(topic string, record *Record) ([]byte, error)
+}
+
+// HeaderEncoder returns the Kafka headers of the message of a record
+type HeaderEncoder interface {
+	Headers(topic string, record *Record) ([]sarama.RecordHeader, error)
+}
+
+func header(key string, value string) sarama.RecordHeader {
+	return sarama.RecordHeader{
+		Key:   []byte(key),
+		Value: []byte(value),
 	}
-	defer producer.Close()
+}
+
+// KafkaSender takes a channel of records and send them to the topic of the router
+type KafkaSender struct {
+	Producer    SyncProducer
+	TopicRouter TopicRouter
+	GTIDStore   interface {
+		Write(checkpoint *Checkpoint) error
+	}
+	// Position before the first record, the GTID of each sent record is added
+	Position GTIDPosition
+	// ValueEncoder is optional, without the data of the record is sent
+	ValueEncoder ValueEncoder
+	// KeyEncoder is optional, without the GTID is used as key
+	KeyEncoder KeyEncoder
+	// HeaderEncoders add headers to the message of each record, not to tombstones and markers
+	HeaderEncoders []HeaderEncoder
+	// Tombstones sends a message with null value after each delete,
+	// so compacted topics remove the key of the deleted row
+	Tombstones bool
+	// Transactions sends all events of a GTID as one batch and writes the checkpoint after the batch.
+	// A transaction ends with the first event of the next GTID. If no event arrived for TransactionTimeout
+	// the events collected so far are sent without end marker and checkpoint.
+	Transactions       bool
+	TransactionTimeout time.Duration
+	// TransactionEnd sends a marker with the event count to each topic of the transaction
+	TransactionEnd bool
+	// NewAsyncProducer is optional, if set Send creates an async producer and does not wait for each acknowledgement.
+	// The checkpoint advances up to the last record whose messages and all messages before are acknowledged.
+	NewAsyncProducer func() (sarama.AsyncProducer, error)
+}
+
+// transactionEnder is implemented by checkpoint stores that flush at the end of each transaction
+type transactionEnder interface {
+	EndTransaction() error
+}
+
+// transaction collects the messages of the events of a GTID
+type transaction struct {
+	gtid        *GTID
+	eventNumber uint64
+	// messages not sent yet, the topics count all events of the transaction
+	messages []*sarama.ProducerMessage
+	// topics in order of the first event and the number of events sent to them
+	topics []string
+	events map[string]int
+}
 
+// Send the given messages to a topic in Kafka
+func (k *KafkaSender) Send(ctx context.Context, ch <-chan *Record) error {
+	defer k.flush()
+	var async *asyncSender
+	var successes <-chan *sarama.ProducerMessage
+	var failures <-chan *sarama.ProducerError
+	if k.NewAsyncProducer != nil {
+		producer, err := k.NewAsyncProducer()
+		if err != nil {
+			return errors.Wrap(err, "create async producer failed")
+		}
+		async = &asyncSender{
+			producer:   producer,
+			checkpoint: k.checkpoint,
+		}
+		defer async.close()
+		successes, failures = producer.Successes(), producer.Errors()
+	}
 	glog.V(3).Infof("wait for lines")
+	var pending *transaction
+	var timeout <-chan time.Time
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case data, ok := <-ch:
+		case msg := <-successes:
+			if err := async.ack(msg); err != nil {
+				return err
+			}
+		case perr := <-failures:
+			return errors.Wrap(perr.Err, "send message to kafka failed")
+		case <-timeout:
+			// the transaction may continue, its messages are sent but it ends only with the next GTID
+			if err := k.sendPending(ctx, async, pending); err != nil {
+				return err
+			}
+			timeout = nil
+		case record, ok := <-ch:
 			if !ok {
+				// an incomplete transaction is not checkpointed and sent again after restart
 				return nil
 			}
-			glog.V(3).Infof("parse '%s'", string(data))
-			gtid, err := k.GTIDExtractor.Parse(data)
+			glog.V(3).Infof("parse record of %s", record.Table)
+			values, err := record.Values()
+			if err != nil {
+				glog.V(3).Infof("Error decoding record: %s", err)
+				continue
+			}
+			gtid, err := GTIDFromValues(values)
 			if err != nil {
 				glog.V(3).Infof("Error extracting gtid: %s", err)
 				//return errors.Wrap(err, "extract gtid failed")
+				continue
+			}
+			var topic string
+			var messages []*sarama.ProducerMessage
+			if record.Dropped {
+				glog.V(3).Infof("skip dropped record of %s from %s", record.Table, gtid)
 			} else {
-				glog.V(3).Infof("send '%s' from %s", string(data), gtid)
-				partition, offset, err := producer.SendMessage(&sarama.ProducerMessage{
-					Topic: k.KafkaTopic,
-					Key:   sarama.StringEncoder(gtid.String()),
-					Value: sarama.ByteEncoder(data),
-				})
+				topic, messages, err = k.messages(record, values, gtid)
 				if err != nil {
-					return errors.Wrap(err, "send message to kafka failed")
+					return err
 				}
-				glog.V(3).Infof("send message successful to %s with partition %d offset %d", k.KafkaTopic, partition, offset)
-				if err := k.GTIDStore.Write(gtid); err != nil {
-					return errors.Wrap(err, "save gtid failed")
+			}
+			if !k.Transactions && async != nil {
+				glog.V(3).Infof("send record of %s from %s", record.Table, gtid)
+				if err := async.send(ctx, messages, gtid, eventNumber(values)); err != nil {
+					return err
 				}
+				continue
+			}
+			if !k.Transactions {
+				glog.V(3).Infof("send record of %s from %s", record.Table, gtid)
+				for _, msg := range messages {
+					partition, offset, err := k.Producer.SendMessage(msg)
+					if err != nil {
+						return errors.Wrap(err, "send message to kafka failed")
+					}
+					glog.V(3).Infof("send message successful to %s with partition %d offset %d", topic, partition, offset)
+				}
+				// at least once: a crash before the write sends the record again after restart
+				if err := k.checkpoint(gtid, eventNumber(values)); err != nil {
+					return err
+				}
+				continue
+			}
+			if pending != nil && *pending.gtid != *gtid {
+				if err := k.sendTransaction(ctx, async, pending); err != nil {
+					return err
+				}
+				pending = nil
+			}
+			if pending == nil {
+				pending = &transaction{
+					gtid:   gtid,
+					events: make(map[string]int),
+				}
+			}
+			pending.eventNumber = eventNumber(values)
+			pending.messages = append(pending.messages, messages...)
+			if !record.Dropped {
+				if pending.events[topic] == 0 {
+					pending.topics = append(pending.topics, topic)
+				}
+				pending.events[topic]++
+			}
+			timeout = time.After(k.transactionTimeout())
+		}
+	}
+}
+
+// messages returns the topic and messages of the record, the message of the event and an optional tombstone
+func (k *KafkaSender) messages(record *Record, values map[string]interface{}, gtid *GTID) (string, []*sarama.ProducerMessage, error) {
+	topic, err := k.TopicRouter.Topic(record)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "get topic failed")
+	}
+	key, err := k.encodeKey(topic, record, gtid)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "encode key failed")
+	}
+	value, err := k.encode(topic, record)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "encode value failed")
+	}
+	var headers []sarama.RecordHeader
+	for _, headerEncoder := range k.HeaderEncoders {
+		recordHeaders, err := headerEncoder.Headers(topic, record)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "encode headers failed")
+		}
+		headers = append(headers, recordHeaders...)
+	}
+	// the time of the event instead of the produce time, sarama uses the produce time if unknown
+	timestamp, _ := eventTime(values)
+	message := &sarama.ProducerMessage{
+		Topic:     topic,
+		Key:       key,
+		Headers:   headers,
+		Timestamp: timestamp,
+	}
+	// a value encoder returns nil for tombstones
+	if value != nil {
+		message.Value = sarama.ByteEncoder(value)
+	}
+	messages := []*sarama.ProducerMessage{message}
+	if k.Tombstones && values["event_type"] == "delete" {
+		messages = append(messages, &sarama.ProducerMessage{
+			Topic:     topic,
+			Key:       key,
+			Timestamp: timestamp,
+		})
+	}
+	return topic, messages, nil
+}
+
+// sendPending sends the messages of the transaction collected so far without end markers and checkpoint
+func (k *KafkaSender) sendPending(ctx context.Context, async *asyncSender, t *transaction) error {
+	messages := t.messages
+	if len(messages) == 0 {
+		return nil
+	}
+	t.messages = nil
+	glog.V(3).Infof("send %d messages of pending transaction %s", len(messages), t.gtid)
+	if async != nil {
+		return async.send(ctx, messages, nil, 0)
+	}
+	if err := k.Producer.SendMessages(messages); err != nil {
+		return errors.Wrapf(err, "send transaction %s to kafka failed", t.gtid)
+	}
+	return nil
+}
+
+// sendTransaction sends all messages of the transaction followed by the end markers
+// and writes the checkpoint once all are acknowledged
+func (k *KafkaSender) sendTransaction(ctx context.Context, async *asyncSender, t *transaction) error {
+	messages := t.messages
+	if k.TransactionEnd {
+		for _, topic := range t.topics {
+			value, err := transactionEndValue(t.gtid, t.events[topic])
+			if err != nil {
+				return errors.Wrap(err, "encode transaction end failed")
 			}
+			messages = append(messages, &sarama.ProducerMessage{
+				Topic: topic,
+				Key:   sarama.StringEncoder(t.gtid.String()),
+				Value: sarama.ByteEncoder(value),
+			})
+		}
+	}
+	glog.V(3).Infof("send transaction %s with %d messages", t.gtid, len(messages))
+	if async != nil {
+		return async.send(ctx, messages, t.gtid, t.eventNumber)
+	}
+	if len(messages) == 0 {
+		return k.checkpoint(t.gtid, t.eventNumber)
+	}
+	if err := k.Producer.SendMessages(messages); err != nil {
+		return errors.Wrapf(err, "send transaction %s to kafka failed", t.gtid)
+	}
+	return k.checkpoint(t.gtid, t.eventNumber)
+}
+
+// checkpoint adds the GTID to the position and writes it with the event number to the store.
+// With transactions each checkpoint ends a transaction.
+func (k *KafkaSender) checkpoint(gtid *GTID, eventNumber uint64) error {
+	k.Position = k.Position.Update(gtid)
+	if err := k.GTIDStore.Write(&Checkpoint{
+		Position:    k.Position,
+		EventNumber: eventNumber,
+	}); err != nil {
+		return errors.Wrap(err, "save gtid failed")
+	}
+	if ender, ok := k.GTIDStore.(transactionEnder); ok && k.Transactions {
+		if err := ender.EndTransaction(); err != nil {
+			return errors.Wrap(err, "end transaction of gtid store failed")
 		}
 	}
+	return nil
+}
+
+func (k *KafkaSender) transactionTimeout() time.Duration {
+	if k.TransactionTimeout <= 0 {
+		return time.Second
+	}
+	return k.TransactionTimeout
+}
+
+// transactionEndValue returns the JSON of the marker sent after the events of a transaction
+func transactionEndValue(gtid *GTID, events int) ([]byte, error) {
+	return encodeJSONObject(
+		[]string{"domain", "server_id", "sequence", "event_type", "event_count"},
+		map[string]interface{}{
+			"domain":      gtid.Domain,
+			"server_id":   gtid.ServerId,
+			"sequence":    gtid.Sequence,
+			"event_type":  "transaction_end",
+			"event_count": events,
+		},
+	)
+}
+
+func (k *KafkaSender) encode(topic string, record *Record) ([]byte, error) {
+	if k.ValueEncoder == nil {
+		return record.Data, nil
+	}
+	return k.ValueEncoder.Encode(topic, record)
+}
+
+func (k *KafkaSender) encodeKey(topic string, record *Record, gtid *GTID) (sarama.Encoder, error) {
+	if k.KeyEncoder == nil {
+		return sarama.StringEncoder(gtid.String()), nil
+	}
+	key, err := k.KeyEncoder.Encode(topic, record)
+	if err != nil {
+		return nil, err
+	}
+	return sarama.ByteEncoder(key), nil
+}
+
+// flush writes pending GTIDs of stores that buffer writes
+func (k *KafkaSender) flush() {
+	store, ok := k.GTIDStore.(interface {
+		Flush() error
+	})
+	if !ok {
+		return
+	}
+	if err := store.Flush(); err != nil {
+		glog.Warningf("flush gtid store failed: %v", err)
+	}
 }
@@ -0,0 +1,416 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+func jsonRecord(data string) *cdc.Record {
+	return &cdc.Record{
+		Table:  cdc.Table{Database: "mydb", Name: "mytable"},
+		Format: "JSON",
+		Data:   []byte(data),
+	}
+}
+
+func sendRecords(sender cdc.Sender, records ...*cdc.Record) error {
+	ch := make(chan *cdc.Record, len(records))
+	for _, record := range records {
+		ch <- record
+	}
+	close(ch)
+	return sender.Send(context.Background(), ch)
+}
+
+var _ = Describe("KafkaSender", func() {
+	var producer *mocks.SyncProducer
+	var gtidStore *cdc.GTIDStore
+	var sender *cdc.KafkaSender
+	var dataDir string
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = ioutil.TempDir("", "kafka-sender")
+		Expect(err).To(BeNil())
+		producer = &mocks.SyncProducer{}
+		gtidStore = &cdc.GTIDStore{DataDir: dataDir}
+		sender = &cdc.KafkaSender{
+			Producer:    producer,
+			TopicRouter: &cdc.TopicTemplate{Template: "mytopic"},
+			GTIDStore:   gtidStore,
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dataDir)
+	})
+
+	It("sends record with gtid as key", func() {
+		err := sendRecords(sender, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "event_type": "insert", "id": 4}`))
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(1))
+		msg := producer.SendMessageArgsForCall(0)
+		Expect(msg.Topic).To(Equal("mytopic"))
+		Expect(msg.Key).To(Equal(sarama.StringEncoder("0-1-58")))
+		Expect(msg.Value).To(Equal(sarama.ByteEncoder(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "event_type": "insert", "id": 4}`)))
+	})
+
+	It("sets the message timestamp to the time of the event", func() {
+		err := sendRecords(sender, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "timestamp": 1541348151}`))
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(1))
+		Expect(producer.SendMessageArgsForCall(0).Timestamp.Equal(time.Unix(1541348151, 0))).To(BeTrue())
+	})
+
+	It("leaves the message timestamp empty without timestamp", func() {
+		err := sendRecords(sender, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58}`))
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageArgsForCall(0).Timestamp.IsZero()).To(BeTrue())
+	})
+
+	It("stores gtid after send", func() {
+		err := sendRecords(sender, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58}`))
+		Expect(err).To(BeNil())
+		gtid, err := gtidStore.Read()
+		Expect(err).To(BeNil())
+		Expect(gtid.String()).To(Equal("0-1-58"))
+	})
+
+	It("skips records without gtid", func() {
+		err := sendRecords(sender, jsonRecord(`banana`))
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(0))
+	})
+
+	It("returns error if send fails", func() {
+		producer.SendMessageReturns(0, 0, errors.New("banana"))
+		err := sendRecords(sender, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58}`))
+		Expect(err).NotTo(BeNil())
+		_, err = gtidStore.Read()
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("sends record to topic of router", func() {
+		sender.TopicRouter = &cdc.TopicTemplate{Template: "cdc.{database}.{table}.{event_type}"}
+		err := sendRecords(sender, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "insert"}`))
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(1))
+		Expect(producer.SendMessageArgsForCall(0).Topic).To(Equal("cdc.mydb.mytable.insert"))
+	})
+
+	It("uses the value encoder", func() {
+		registry := &mocks.SchemaRegistry{}
+		registry.RegisterReturns(1, nil)
+		sender.ValueEncoder = &cdc.ConfluentEncoder{
+			SchemaRegistry: registry,
+		}
+		record := avroRecord(map[string]interface{}{"domain": 0, "server_id": 1, "sequence": 58})
+		err := sendRecords(sender, record)
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(1))
+		msg := producer.SendMessageArgsForCall(0)
+		Expect(msg.Value).To(Equal(sarama.ByteEncoder(append([]byte{0, 0, 0, 0, 1}, record.Data...))))
+	})
+})
+
+var _ = Describe("KafkaSender with key encoder", func() {
+	It("uses the key encoder", func() {
+		producer := &mocks.SyncProducer{}
+		dataDir, err := ioutil.TempDir("", "kafka-sender")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dataDir)
+		sender := &cdc.KafkaSender{
+			Producer:    producer,
+			TopicRouter: &cdc.TopicTemplate{Template: "mytopic"},
+			GTIDStore:   &cdc.GTIDStore{DataDir: dataDir},
+			KeyEncoder: &cdc.PrimaryKeyEncoder{
+				Format: cdc.KeyFormatString,
+				Columns: map[cdc.Table][]string{
+					{Database: "mydb", Name: "mytable"}: {"id"},
+				},
+			},
+		}
+		err = sendRecords(sender, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "id": 4}`))
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(1))
+		Expect(producer.SendMessageArgsForCall(0).Key).To(Equal(sarama.ByteEncoder("4")))
+	})
+})
+
+var _ = Describe("KafkaSender with tombstones", func() {
+	var producer *mocks.SyncProducer
+	var sender *cdc.KafkaSender
+	var dataDir string
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = ioutil.TempDir("", "kafka-sender")
+		Expect(err).To(BeNil())
+		producer = &mocks.SyncProducer{}
+		sender = &cdc.KafkaSender{
+			Producer:    producer,
+			TopicRouter: &cdc.TopicTemplate{Template: "mytopic"},
+			GTIDStore:   &cdc.GTIDStore{DataDir: dataDir},
+			KeyEncoder:  &cdc.PrimaryKeyEncoder{Format: cdc.KeyFormatString, Columns: map[cdc.Table][]string{{Database: "mydb", Name: "mytable"}: {"id"}}},
+			Tombstones:  true,
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dataDir)
+	})
+
+	It("sends tombstone after delete", func() {
+		err := sendRecords(sender, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "delete", "id": 4}`))
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(2))
+		msg := producer.SendMessageArgsForCall(0)
+		Expect(msg.Key).To(Equal(sarama.ByteEncoder("4")))
+		Expect(msg.Value).NotTo(BeNil())
+		tombstone := producer.SendMessageArgsForCall(1)
+		Expect(tombstone.Topic).To(Equal("mytopic"))
+		Expect(tombstone.Key).To(Equal(sarama.ByteEncoder("4")))
+		Expect(tombstone.Value).To(BeNil())
+	})
+
+	It("sends no tombstone for other event types", func() {
+		err := sendRecords(sender, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "update_after", "id": 4}`))
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(1))
+	})
+
+	It("sends no tombstone if disabled", func() {
+		sender.Tombstones = false
+		err := sendRecords(sender, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "delete", "id": 4}`))
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(1))
+	})
+
+	It("does not store gtid if tombstone fails", func() {
+		producer.SendMessageStub = func(msg *sarama.ProducerMessage) (int32, int64, error) {
+			if msg.Value == nil {
+				return 0, 0, errors.New("banana")
+			}
+			return 0, 0, nil
+		}
+		err := sendRecords(sender, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "delete", "id": 4}`))
+		Expect(err).NotTo(BeNil())
+		_, err = sender.GTIDStore.(*cdc.GTIDStore).Read()
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("KafkaSender with flushing store", func() {
+	It("flushes pending gtid when done", func() {
+		backend := &cdc.MemoryCheckpointStore{}
+		sender := &cdc.KafkaSender{
+			Producer:    &mocks.SyncProducer{},
+			TopicRouter: &cdc.TopicTemplate{Template: "mytopic"},
+			GTIDStore:   &cdc.FlushingCheckpointStore{Store: backend, Events: 100},
+		}
+		err := sendRecords(sender, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58}`))
+		Expect(err).To(BeNil())
+		gtid, err := backend.Read()
+		Expect(err).To(BeNil())
+		Expect(gtid.String()).To(Equal("0-1-58"))
+	})
+
+	It("flushes the last transaction of an idle table by interval", func() {
+		backend := &cdc.MemoryCheckpointStore{}
+		sender := &cdc.KafkaSender{
+			Producer:    &mocks.SyncProducer{},
+			TopicRouter: &cdc.TopicTemplate{Template: "mytopic"},
+			GTIDStore:   &cdc.FlushingCheckpointStore{Store: backend, TransactionEnd: true, Interval: 10 * time.Millisecond},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ch := make(chan *cdc.Record, 1)
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`)
+		done := make(chan error)
+		go func() {
+			done <- sender.Send(ctx, ch)
+		}()
+		Eventually(func() string {
+			checkpoint, err := backend.Read()
+			if err != nil {
+				return err.Error()
+			}
+			return checkpoint.String()
+		}).Should(Equal("0-1-58#1"))
+		cancel()
+		Expect(<-done).To(BeNil())
+	})
+})
+
+var _ = Describe("KafkaSender with position", func() {
+	It("adds the gtids of sent records to the position", func() {
+		store := &cdc.MemoryCheckpointStore{}
+		position, err := cdc.ParseGTIDPosition("0-1-58,1-2-10")
+		Expect(err).To(BeNil())
+		sender := &cdc.KafkaSender{
+			Producer:    &mocks.SyncProducer{},
+			TopicRouter: &cdc.TopicTemplate{Template: "mytopic"},
+			GTIDStore:   store,
+			Position:    position,
+		}
+		err = sendRecords(sender,
+			jsonRecord(`{"domain": 1, "server_id": 2, "sequence": 11}`),
+			jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 59}`),
+		)
+		Expect(err).To(BeNil())
+		result, err := store.Read()
+		Expect(err).To(BeNil())
+		Expect(result.String()).To(Equal("1-2-11,0-1-59"))
+	})
+})
+
+var _ = Describe("KafkaSender checkpoint", func() {
+	It("stores the event number of the last sent record", func() {
+		store := &cdc.MemoryCheckpointStore{}
+		sender := &cdc.KafkaSender{
+			Producer:    &mocks.SyncProducer{},
+			TopicRouter: &cdc.TopicTemplate{Template: "mytopic"},
+			GTIDStore:   store,
+		}
+		err := sendRecords(sender,
+			jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`),
+			jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2}`),
+		)
+		Expect(err).To(BeNil())
+		checkpoint, err := store.Read()
+		Expect(err).To(BeNil())
+		Expect(checkpoint.String()).To(Equal("0-1-58#2"))
+	})
+
+	It("advances the checkpoint past dropped records without sending them", func() {
+		store := &cdc.MemoryCheckpointStore{}
+		producer := &mocks.SyncProducer{}
+		sender := &cdc.KafkaSender{
+			Producer:    producer,
+			TopicRouter: &cdc.TopicTemplate{Template: "mytopic"},
+			GTIDStore:   store,
+		}
+		record := jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`)
+		record.Dropped = true
+		Expect(sendRecords(sender, record)).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(0))
+		checkpoint, err := store.Read()
+		Expect(err).To(BeNil())
+		Expect(checkpoint.String()).To(Equal("0-1-58#1"))
+	})
+})
+
+var _ = Describe("KafkaSender with transactions", func() {
+	var producer *mocks.SyncProducer
+	var store *cdc.MemoryCheckpointStore
+	var sender *cdc.KafkaSender
+
+	BeforeEach(func() {
+		producer = &mocks.SyncProducer{}
+		store = &cdc.MemoryCheckpointStore{}
+		sender = &cdc.KafkaSender{
+			Producer:           producer,
+			TopicRouter:        &cdc.TopicTemplate{Template: "mytopic"},
+			GTIDStore:          store,
+			Transactions:       true,
+			TransactionTimeout: time.Hour,
+		}
+	})
+
+	It("sends the events of a gtid as one batch", func() {
+		err := sendRecords(sender,
+			jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`),
+			jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2}`),
+			jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 59, "event_number": 1}`),
+		)
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(0))
+		Expect(producer.SendMessagesCallCount()).To(Equal(1))
+		Expect(producer.SendMessagesArgsForCall(0)).To(HaveLen(2))
+		checkpoint, err := store.Read()
+		Expect(err).To(BeNil())
+		Expect(checkpoint.String()).To(Equal("0-1-58#2"))
+	})
+
+	It("sends transaction end marker with event count", func() {
+		sender.TransactionEnd = true
+		err := sendRecords(sender,
+			jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`),
+			jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2}`),
+			jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 59, "event_number": 1}`),
+		)
+		Expect(err).To(BeNil())
+		messages := producer.SendMessagesArgsForCall(0)
+		Expect(messages).To(HaveLen(3))
+		Expect(messages[2].Topic).To(Equal("mytopic"))
+		Expect(messages[2].Key).To(Equal(sarama.StringEncoder("0-1-58")))
+		Expect(messages[2].Value).To(Equal(sarama.ByteEncoder(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "transaction_end", "event_count": 2}`)))
+	})
+
+	It("sends the events without marker and checkpoint if no further event arrives", func() {
+		sender.TransactionEnd = true
+		sender.TransactionTimeout = 10 * time.Millisecond
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ch := make(chan *cdc.Record)
+		done := make(chan error)
+		go func() {
+			done <- sender.Send(ctx, ch)
+		}()
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`)
+		Eventually(producer.SendMessagesCallCount).Should(Equal(1))
+		Expect(producer.SendMessagesArgsForCall(0)).To(HaveLen(1))
+		_, err := store.Read()
+		Expect(err).To(Equal(cdc.ErrNoCheckpoint))
+
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2}`)
+		ch <- jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 59, "event_number": 1}`)
+		Eventually(producer.SendMessagesCallCount).Should(Equal(2))
+		messages := producer.SendMessagesArgsForCall(1)
+		Expect(messages).To(HaveLen(2))
+		Expect(messages[1].Value).To(Equal(sarama.ByteEncoder(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "transaction_end", "event_count": 2}`)))
+		checkpoint, err := store.Read()
+		Expect(err).To(BeNil())
+		Expect(checkpoint.String()).To(Equal("0-1-58#2"))
+		cancel()
+		Expect(<-done).To(BeNil())
+	})
+
+	It("checkpoints transactions of dropped records without sending and without markers", func() {
+		sender.TransactionEnd = true
+		first := jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`)
+		first.Dropped = true
+		second := jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2}`)
+		second.Dropped = true
+		err := sendRecords(sender, first, second, jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 59, "event_number": 1}`))
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessagesCallCount()).To(Equal(0))
+		checkpoint, err := store.Read()
+		Expect(err).To(BeNil())
+		Expect(checkpoint.String()).To(Equal("0-1-58#2"))
+	})
+
+	It("does not write the checkpoint if the batch fails", func() {
+		producer.SendMessagesReturns(errors.New("banana"))
+		err := sendRecords(sender,
+			jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1}`),
+			jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 59, "event_number": 1}`),
+		)
+		Expect(err).NotTo(BeNil())
+		_, err = store.Read()
+		Expect(err).To(Equal(cdc.ErrNoCheckpoint))
+	})
+})
@@ -0,0 +1,194 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	"github.com/pkg/errors"
+)
+
+// Formats of the Kafka message key
+const (
+	KeyFormatGTID   = "gtid"
+	KeyFormatString = "string"
+	KeyFormatJSON   = "json"
+	KeyFormatAvro   = "avro"
+)
+
+// KeyEncoder converts a record into the key of a Kafka message
+type KeyEncoder interface {
+	Encode(topic string, record *Record) ([]byte, error)
+}
+
+// ValidKeyFormat returns true if the given key format is known
+func ValidKeyFormat(format string) bool {
+	switch format {
+	case KeyFormatGTID, KeyFormatString, KeyFormatJSON, KeyFormatAvro:
+		return true
+	default:
+		return false
+	}
+}
+
+// metadataFields are added by Maxscale to every record and are not columns of the table
+var metadataFields = map[string]bool{
+	"domain":       true,
+	"server_id":    true,
+	"sequence":     true,
+	"event_number": true,
+	"timestamp":    true,
+	"event_type":   true,
+}
+
+var invalidAvroNameChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// PrimaryKeyEncoder builds the message key from the primary key columns of the table.
+// Maxscale does not mark primary keys in its schema, the columns must be configured for each table.
+type PrimaryKeyEncoder struct {
+	Format string // string, json or avro
+	// Columns of the primary key per table
+	Columns map[Table][]string
+	// Confluent is optional, if set Avro keys are written in the Confluent wire format
+	Confluent *ConfluentEncoder
+
+	mux     sync.Mutex
+	schemas map[*avro.Schema]*avro.Schema
+}
+
+// Encode the key of the record
+func (p *PrimaryKeyEncoder) Encode(topic string, record *Record) ([]byte, error) {
+	columns, err := p.columns(record)
+	if err != nil {
+		return nil, err
+	}
+	values, err := record.Values()
+	if err != nil {
+		return nil, errors.Wrap(err, "get values failed")
+	}
+	for _, column := range columns {
+		if _, ok := values[column]; !ok {
+			return nil, errors.Errorf("key column %s missing in record of %s", column, record.Table)
+		}
+	}
+	switch p.Format {
+	case KeyFormatString:
+		var parts []string
+		for _, column := range columns {
+			parts = append(parts, keyString(values[column]))
+		}
+		return []byte(strings.Join(parts, ",")), nil
+	case KeyFormatJSON:
+		return encodeJSONObject(columns, values)
+	case KeyFormatAvro:
+		schema, err := p.keySchema(record, columns)
+		if err != nil {
+			return nil, err
+		}
+		buf := &bytes.Buffer{}
+		if err := avro.Encode(schema, buf, values); err != nil {
+			return nil, errors.Wrap(err, "encode avro key failed")
+		}
+		if p.Confluent == nil {
+			return buf.Bytes(), nil
+		}
+		return p.Confluent.EncodeKey(topic, record, schema, buf.Bytes())
+	default:
+		return nil, errors.Errorf("unsupported key format %s", p.Format)
+	}
+}
+
+func (p *PrimaryKeyEncoder) columns(record *Record) ([]string, error) {
+	columns, ok := p.Columns[record.Table]
+	if !ok {
+		return nil, errors.Errorf("no key columns configured for %s", record.Table)
+	}
+	return columns, nil
+}
+
+// keySchema returns the Avro schema of the key, built from the fields of the record schema
+func (p *PrimaryKeyEncoder) keySchema(record *Record, columns []string) (*avro.Schema, error) {
+	if record.Schema == nil {
+		return nil, errors.New("schema missing")
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if schema, ok := p.schemas[record.Schema]; ok {
+		return schema, nil
+	}
+	var fields []*avro.Field
+	for _, column := range columns {
+		field := record.Schema.Field(column)
+		if field == nil {
+			return nil, errors.Errorf("key column %s missing in schema of %s", column, record.Table)
+		}
+		fields = append(fields, field)
+	}
+	schema, err := avro.NewRecordSchema(
+		avroName(record.Table.Database),
+		avroName(record.Table.Name)+"_key",
+		fields,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "create key schema failed")
+	}
+	if p.schemas == nil {
+		p.schemas = make(map[*avro.Schema]*avro.Schema)
+	}
+	p.schemas[record.Schema] = schema
+	return schema, nil
+}
+
+func avroName(name string) string {
+	name = invalidAvroNameChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return "_" + name
+	}
+	return name
+}
+
+func keyString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ParseKeyColumns parses a comma separated list of DATABASE.TABLE=COLUMN+COLUMN
+func ParseKeyColumns(keyColumns string) (map[Table][]string, error) {
+	result := make(map[Table][]string)
+	for _, entry := range strings.Split(keyColumns, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("parse key columns %s failed", entry)
+		}
+		table, err := ParseTable(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		var columns []string
+		for _, column := range strings.Split(parts[1], "+") {
+			column = strings.TrimSpace(column)
+			if column == "" {
+				return nil, errors.Errorf("empty column in key columns %s", entry)
+			}
+			columns = append(columns, column)
+		}
+		result[*table] = columns
+	}
+	return result, nil
+}
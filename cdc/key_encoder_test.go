@@ -0,0 +1,136 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PrimaryKeyEncoder", func() {
+	var values map[string]interface{}
+	var namesTable cdc.Table
+
+	BeforeEach(func() {
+		namesTable = cdc.Table{Database: "test", Name: "names"}
+		values = map[string]interface{}{
+			"domain":       0,
+			"server_id":    1,
+			"sequence":     58,
+			"event_number": 1,
+			"timestamp":    1541348151,
+			"event_type":   "insert",
+			"id":           4,
+			"name":         "Hello",
+		}
+	})
+
+	It("returns error without configured columns", func() {
+		encoder := &cdc.PrimaryKeyEncoder{Format: cdc.KeyFormatString}
+		_, err := encoder.Encode("mytopic", namesRecord("JSON", values))
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("joins configured columns as string", func() {
+		encoder := &cdc.PrimaryKeyEncoder{
+			Format:  cdc.KeyFormatString,
+			Columns: map[cdc.Table][]string{namesTable: {"id", "name"}},
+		}
+		key, err := encoder.Encode("mytopic", namesRecord("AVRO", values))
+		Expect(err).To(BeNil())
+		Expect(string(key)).To(Equal("4,Hello"))
+	})
+
+	It("encodes configured columns as json object", func() {
+		encoder := &cdc.PrimaryKeyEncoder{
+			Format:  cdc.KeyFormatJSON,
+			Columns: map[cdc.Table][]string{namesTable: {"name", "id"}},
+		}
+		key, err := encoder.Encode("mytopic", namesRecord("JSON", values))
+		Expect(err).To(BeNil())
+		Expect(string(key)).To(Equal(`{"name": "Hello", "id": 4}`))
+	})
+
+	It("encodes key as avro", func() {
+		encoder := &cdc.PrimaryKeyEncoder{
+			Format:  cdc.KeyFormatAvro,
+			Columns: map[cdc.Table][]string{namesTable: {"id", "name"}},
+		}
+		key, err := encoder.Encode("mytopic", namesRecord("JSON", values))
+		Expect(err).To(BeNil())
+		schema, err := avro.ParseSchema([]byte(`{"type":"record","name":"names_key","namespace":"test","fields":[
+			{"name":"id","type":"int"},
+			{"name":"name","type":["null","string"]}
+		]}`))
+		Expect(err).To(BeNil())
+		value, err := avro.Decode(schema, bytes.NewReader(key))
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal(map[string]interface{}{"id": int32(4), "name": "Hello"}))
+	})
+
+	It("registers avro key schema", func() {
+		registry := &mocks.SchemaRegistry{}
+		registry.RegisterReturns(7, nil)
+		encoder := &cdc.PrimaryKeyEncoder{
+			Format:    cdc.KeyFormatAvro,
+			Columns:   map[cdc.Table][]string{namesTable: {"id"}},
+			Confluent: &cdc.ConfluentEncoder{SchemaRegistry: registry},
+		}
+		key, err := encoder.Encode("mytopic", namesRecord("AVRO", values))
+		Expect(err).To(BeNil())
+		Expect(key[0]).To(Equal(byte(0)))
+		Expect(binary.BigEndian.Uint32(key[1:5])).To(Equal(uint32(7)))
+		Expect(registry.RegisterCallCount()).To(Equal(1))
+		subject, schema := registry.RegisterArgsForCall(0)
+		Expect(subject).To(Equal("mytopic-key"))
+		Expect(schema.FullName()).To(Equal("test.names_key"))
+		Expect(schema.Fields).To(HaveLen(1))
+	})
+
+	It("returns error if key column is missing", func() {
+		encoder := &cdc.PrimaryKeyEncoder{
+			Format:  cdc.KeyFormatString,
+			Columns: map[cdc.Table][]string{namesTable: {"banana"}},
+		}
+		_, err := encoder.Encode("mytopic", namesRecord("JSON", values))
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error without columns and schema", func() {
+		encoder := &cdc.PrimaryKeyEncoder{Format: cdc.KeyFormatString}
+		_, err := encoder.Encode("mytopic", jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "id": 4}`))
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("ParseKeyColumns", func() {
+	It("parses columns per table", func() {
+		columns, err := cdc.ParseKeyColumns("shop.orders=id, shop.items=order_id+item_id")
+		Expect(err).To(BeNil())
+		Expect(columns).To(HaveLen(2))
+		Expect(columns[cdc.Table{Database: "shop", Name: "orders"}]).To(Equal([]string{"id"}))
+		Expect(columns[cdc.Table{Database: "shop", Name: "items"}]).To(Equal([]string{"order_id", "item_id"}))
+	})
+
+	It("returns empty map for empty string", func() {
+		columns, err := cdc.ParseKeyColumns("")
+		Expect(err).To(BeNil())
+		Expect(columns).To(BeEmpty())
+	})
+
+	for _, invalid := range []string{"shop.orders", "shop=id", "shop.orders=id+", "shop.orders="} {
+		invalid := invalid
+		It("returns error for "+invalid, func() {
+			_, err := cdc.ParseKeyColumns(invalid)
+			Expect(err).NotTo(BeNil())
+		})
+	}
+})
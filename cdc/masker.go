@@ -0,0 +1,271 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	"github.com/pkg/errors"
+)
+
+// Mask actions
+const (
+	// MaskRedact replaces the value with ***
+	MaskRedact = "redact"
+	// MaskTruncate keeps the first characters of the value
+	MaskTruncate = "truncate"
+	// MaskHash replaces the value with the hex HMAC-SHA256 of the key, equal values have equal hashes
+	MaskHash = "hash"
+	// MaskTokenize replaces the value with a token that Detokenize reverts with the key
+	MaskTokenize = "tokenize"
+)
+
+const redacted = "***"
+
+// MaskRule masks the columns matching the column pattern
+type MaskRule struct {
+	Column string
+	Action string
+	// Length of truncate
+	Length int
+}
+
+// ParseMaskRule parses COLUMN:ACTION or COLUMN:truncate:LENGTH, e.g. email:hash or phone:truncate:4
+func ParseMaskRule(rule string) (*MaskRule, error) {
+	parts := strings.Split(rule, ":")
+	if len(parts) < 2 || parts[0] == "" {
+		return nil, errors.Errorf("parse mask rule %s failed", rule)
+	}
+	if _, err := path.Match(parts[0], ""); err != nil {
+		return nil, errors.Wrapf(err, "invalid column pattern in mask rule %s", rule)
+	}
+	result := &MaskRule{
+		Column: parts[0],
+		Action: parts[1],
+	}
+	switch result.Action {
+	case MaskRedact, MaskHash, MaskTokenize:
+		if len(parts) != 2 {
+			return nil, errors.Errorf("unexpected argument in mask rule %s", rule)
+		}
+	case MaskTruncate:
+		if len(parts) != 3 {
+			return nil, errors.Errorf("length missing in mask rule %s", rule)
+		}
+		length, err := strconv.Atoi(parts[2])
+		if err != nil || length < 0 {
+			return nil, errors.Errorf("invalid length in mask rule %s", rule)
+		}
+		result.Length = length
+	default:
+		return nil, errors.Errorf("unknown action in mask rule %s", rule)
+	}
+	return result, nil
+}
+
+// ParseMaskRules parses the rules of each table pattern, e.g. shop.customers=email:hash+phone:truncate:4
+func ParseMaskRules(rules string) (map[string][]*MaskRule, error) {
+	lists, err := ParseTableLists(rules)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]*MaskRule)
+	for pattern, values := range lists {
+		for _, value := range values {
+			rule, err := ParseMaskRule(value)
+			if err != nil {
+				return nil, err
+			}
+			result[pattern] = append(result[pattern], rule)
+		}
+	}
+	return result, nil
+}
+
+// Masker masks columns of records by rules, so sensitive values never reach Kafka in clear text.
+// Masked columns become strings, in AVRO format the schema is changed accordingly.
+// The metadata fields of Maxscale are never masked.
+type Masker struct {
+	Rules []*MaskRule
+	// Key of hash and tokenize
+	Key []byte
+
+	schemas map[*avro.Schema]*avro.Schema
+}
+
+// Process masks the values of all columns matching a rule
+func (m *Masker) Process(record *Record) ([]*Record, error) {
+	values, err := record.Values()
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode %s record failed", record.Format)
+	}
+	masked := false
+	for name, value := range values {
+		rule := m.rule(name)
+		if rule == nil {
+			continue
+		}
+		if values[name], err = m.mask(rule, value); err != nil {
+			return nil, errors.Wrapf(err, "mask %s of %s failed", name, record.Table)
+		}
+		masked = true
+	}
+	if !masked {
+		return []*Record{record}, nil
+	}
+	result := *record
+	if record.Schema != nil {
+		if result.Schema, err = m.schema(record.Schema); err != nil {
+			return nil, errors.Wrapf(err, "mask schema of %s failed", record.Table)
+		}
+	}
+	if err := result.SetValues(values); err != nil {
+		return nil, errors.Wrapf(err, "encode %s record failed", record.Format)
+	}
+	return []*Record{&result}, nil
+}
+
+// rule returns the first rule matching the column or nil
+func (m *Masker) rule(name string) *MaskRule {
+	if metadataFields[name] {
+		return nil
+	}
+	for _, rule := range m.Rules {
+		if ok, _ := path.Match(rule.Column, name); ok {
+			return rule
+		}
+	}
+	return nil
+}
+
+// mask returns the masked value as string, null stays null
+func (m *Masker) mask(rule *MaskRule, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	var plain string
+	switch v := value.(type) {
+	case []byte:
+		plain = string(v)
+	default:
+		plain = fmt.Sprint(v)
+	}
+	switch rule.Action {
+	case MaskRedact:
+		return redacted, nil
+	case MaskTruncate:
+		runes := []rune(plain)
+		if len(runes) > rule.Length {
+			runes = runes[:rule.Length]
+		}
+		return string(runes), nil
+	case MaskHash:
+		mac := hmac.New(sha256.New, m.Key)
+		mac.Write([]byte(plain))
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	case MaskTokenize:
+		return Tokenize(m.Key, plain)
+	default:
+		return nil, errors.Errorf("unknown action %s", rule.Action)
+	}
+}
+
+// schema returns the schema with masked fields as strings, the result is cached per schema
+func (m *Masker) schema(schema *avro.Schema) (*avro.Schema, error) {
+	if masked, ok := m.schemas[schema]; ok {
+		return masked, nil
+	}
+	var fields []*avro.Field
+	for _, field := range schema.Fields {
+		if m.rule(field.Name) == nil {
+			fields = append(fields, field)
+			continue
+		}
+		fieldType := &avro.Schema{Type: avro.TypeString}
+		if field.Type.Nullable() {
+			fieldType = &avro.Schema{
+				Type:  avro.TypeUnion,
+				Types: []*avro.Schema{{Type: avro.TypeNull}, fieldType},
+			}
+		}
+		fields = append(fields, &avro.Field{
+			Name:       field.Name,
+			Type:       fieldType,
+			Attributes: field.Attributes,
+		})
+	}
+	masked, err := avro.NewRecordSchema(schema.Namespace, schema.Name, fields)
+	if err != nil {
+		return nil, err
+	}
+	if m.schemas == nil {
+		m.schemas = make(map[*avro.Schema]*avro.Schema)
+	}
+	m.schemas[schema] = masked
+	return masked, nil
+}
+
+// Tokenize encrypts the value deterministically with AES-GCM, the nonce is derived from the value.
+// Equal values have equal tokens, so joins still work, and holders of the key can revert them.
+func Tokenize(key []byte, value string) (string, error) {
+	aead, err := tokenCipher(key)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, deriveKey(key, "nonce"))
+	mac.Write([]byte(value))
+	nonce := mac.Sum(nil)[:aead.NonceSize()]
+	token := aead.Seal(nonce, nonce, []byte(value), nil)
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// Detokenize returns the value of the token
+func Detokenize(key []byte, token string) (string, error) {
+	aead, err := tokenCipher(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", errors.Wrap(err, "decode token failed")
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("token too short")
+	}
+	value, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.Wrap(err, "decrypt token failed")
+	}
+	return string(value), nil
+}
+
+func tokenCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveKey(key, "tokenize"))
+	if err != nil {
+		return nil, errors.Wrap(err, "create cipher failed")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "create gcm failed")
+	}
+	return aead, nil
+}
+
+// deriveKey returns a 32 byte key for the given purpose, so hash, nonce and cipher never share a key
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
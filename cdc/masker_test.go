@@ -0,0 +1,129 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MaskRule", func() {
+	It("parses rules", func() {
+		rule, err := cdc.ParseMaskRule("phone:truncate:4")
+		Expect(err).To(BeNil())
+		Expect(rule).To(Equal(&cdc.MaskRule{Column: "phone", Action: cdc.MaskTruncate, Length: 4}))
+		rule, err = cdc.ParseMaskRule("email:hash")
+		Expect(err).To(BeNil())
+		Expect(rule).To(Equal(&cdc.MaskRule{Column: "email", Action: cdc.MaskHash}))
+	})
+
+	It("returns error for invalid rules", func() {
+		for _, rule := range []string{"email", ":hash", "email:banana", "phone:truncate", "phone:truncate:x", "email:hash:4", "[:redact"} {
+			_, err := cdc.ParseMaskRule(rule)
+			Expect(err).NotTo(BeNil(), rule)
+		}
+	})
+
+	It("parses rules by table pattern", func() {
+		rules, err := cdc.ParseMaskRules("shop.customers=email:hash+phone:truncate:4")
+		Expect(err).To(BeNil())
+		Expect(rules["shop.customers"]).To(HaveLen(2))
+	})
+})
+
+var _ = Describe("Masker", func() {
+	var masker *cdc.Masker
+	key := []byte("0123456789abcdef")
+	values := func() map[string]interface{} {
+		return map[string]interface{}{
+			"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "timestamp": 1541348151, "event_type": "insert", "id": 4, "name": "Benjamin",
+		}
+	}
+	process := func(record *cdc.Record) map[string]interface{} {
+		records, err := masker.Process(record)
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+		result, err := records[0].Values()
+		Expect(err).To(BeNil())
+		return result
+	}
+
+	BeforeEach(func() {
+		masker = &cdc.Masker{Key: key}
+	})
+
+	It("redacts values", func() {
+		masker.Rules = []*cdc.MaskRule{{Column: "name", Action: cdc.MaskRedact}}
+		Expect(process(namesRecord("JSON", values()))).To(HaveKeyWithValue("name", "***"))
+	})
+
+	It("truncates values", func() {
+		masker.Rules = []*cdc.MaskRule{{Column: "name", Action: cdc.MaskTruncate, Length: 3}}
+		Expect(process(namesRecord("JSON", values()))).To(HaveKeyWithValue("name", "Ben"))
+	})
+
+	It("hashes values with hmac", func() {
+		masker.Rules = []*cdc.MaskRule{{Column: "name", Action: cdc.MaskHash}}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte("Benjamin"))
+		Expect(process(namesRecord("JSON", values()))).To(HaveKeyWithValue("name", hex.EncodeToString(mac.Sum(nil))))
+	})
+
+	It("tokenizes values deterministically and reversible", func() {
+		masker.Rules = []*cdc.MaskRule{{Column: "name", Action: cdc.MaskTokenize}}
+		first := process(namesRecord("JSON", values()))
+		second := process(namesRecord("JSON", values()))
+		Expect(first["name"]).To(Equal(second["name"]))
+		Expect(first["name"]).NotTo(Equal("Benjamin"))
+		value, err := cdc.Detokenize(key, first["name"].(string))
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("Benjamin"))
+		_, err = cdc.Detokenize([]byte("fedcba9876543210"), first["name"].(string))
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("never masks metadata fields", func() {
+		masker.Rules = []*cdc.MaskRule{{Column: "*", Action: cdc.MaskRedact}}
+		result := process(namesRecord("JSON", values()))
+		Expect(result).To(HaveKeyWithValue("id", "***"))
+		Expect(result).To(HaveKeyWithValue("event_type", "insert"))
+		Expect(result["sequence"]).To(BeEquivalentTo("58"))
+	})
+
+	It("keeps null values", func() {
+		masker.Rules = []*cdc.MaskRule{{Column: "name", Action: cdc.MaskHash}}
+		input := values()
+		input["name"] = nil
+		Expect(process(namesRecord("AVRO", input))).To(HaveKeyWithValue("name", BeNil()))
+	})
+
+	It("changes masked avro fields to string", func() {
+		masker.Rules = []*cdc.MaskRule{{Column: "id", Action: cdc.MaskHash}, {Column: "name", Action: cdc.MaskRedact}}
+		record := namesRecord("AVRO", values())
+		records, err := masker.Process(record)
+		Expect(err).To(BeNil())
+		Expect(records[0].Schema.Field("id").Type.Type).To(Equal(avro.TypeString))
+		Expect(records[0].Schema.Field("name").Type.Nullable()).To(BeTrue())
+		result, err := records[0].Values()
+		Expect(err).To(BeNil())
+		Expect(result["id"]).To(HaveLen(64))
+		Expect(result).To(HaveKeyWithValue("name", "***"))
+		Expect(record.Schema.Field("id").Type.Type).To(Equal(avro.TypeInt))
+	})
+
+	It("returns the record unchanged without matching rule", func() {
+		masker.Rules = []*cdc.MaskRule{{Column: "email", Action: cdc.MaskHash}}
+		record := namesRecord("JSON", values())
+		records, err := masker.Process(record)
+		Expect(err).To(BeNil())
+		Expect(records[0]).To(BeIdenticalTo(record))
+	})
+})
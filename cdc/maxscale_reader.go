@@ -10,9 +10,11 @@ import (
 	"context"
 	"crypto/sha1"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
 )
@@ -37,7 +39,7 @@ type MaxscaleReader struct {
 
 // Read all cdc and send them to the given channel
 // https://mariadb.com/resources/blog/how-to-stream-change-data-through-mariadb-maxscale-using-cdc-api/
-func (r *MaxscaleReader) Read(ctx context.Context, gtid *GTID, ch chan<- []byte) error {
+func (r *MaxscaleReader) Read(ctx context.Context, gtid *GTID, ch chan<- *Record) error {
 
 	conn, err := r.Dialer.Dial(ctx)
 	if err != nil {
@@ -74,29 +76,15 @@ func (r *MaxscaleReader) Read(ctx context.Context, gtid *GTID, ch chan<- []byte)
 	glog.V(1).Infof("start streaming of %s %s %s %s", r.Database, r.Table, r.Version, gtid)
 	go func() {
 		reader := bufio.NewReader(conn)
-		for {
-			line, err := reader.ReadBytes('\n')
-			if err == io.EOF {
-				glog.V(1).Infof("connection closed")
-				errs <- nil
-				return
-			}
-			if err != nil {
-				errs <- errors.Wrap(err, "read line failed")
-				return
-			}
-			if startsWith(line, []byte("ERR")) {
-				errs <- errors.Errorf("got error: %s", string(line))
-				return
-			}
-			if glog.V(4) {
-				glog.Infof("read %s", string(line))
-			}
-			select {
-			case ch <- line:
-			case <-ctx.Done():
-				return
-			}
+		var err error
+		if r.Format == "AVRO" {
+			err = r.readAvro(ctx, reader, ch)
+		} else {
+			err = r.readJSON(ctx, reader, ch)
+		}
+		select {
+		case errs <- err:
+		case <-ctx.Done():
 		}
 	}()
 
@@ -108,6 +96,90 @@ func (r *MaxscaleReader) Read(ctx context.Context, gtid *GTID, ch chan<- []byte)
 	}
 }
 
+// readJSON reads one record per line. Schema lines are not send to the channel,
+// but used as schema for all following records.
+func (r *MaxscaleReader) readJSON(ctx context.Context, reader *bufio.Reader, ch chan<- *Record) error {
+	var schema *avro.Schema
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			glog.V(1).Infof("connection closed")
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "read line failed")
+		}
+		if startsWith(line, []byte("ERR")) {
+			return errors.Errorf("got error: %s", string(line))
+		}
+		if glog.V(4) {
+			glog.Infof("read %s", string(line))
+		}
+		if isSchema(line) {
+			schema, err = avro.ParseSchema(line)
+			if err != nil {
+				return errors.Wrap(err, "parse schema failed")
+			}
+			glog.V(2).Infof("got schema for %s.%s", r.Database, r.Table)
+			continue
+		}
+		if err := r.send(ctx, ch, schema, line); err != nil {
+			return err
+		}
+	}
+}
+
+// readAvro reads the Avro object container and sends each record to the channel
+func (r *MaxscaleReader) readAvro(ctx context.Context, reader *bufio.Reader, ch chan<- *Record) error {
+	prefix, _ := reader.Peek(3)
+	if startsWith(prefix, []byte("ERR")) {
+		line, _ := reader.ReadBytes('\n')
+		return errors.Errorf("got error: %s", string(line))
+	}
+	container := avro.NewContainerReader(reader)
+	for {
+		data, _, err := container.Next()
+		if err == io.EOF {
+			glog.V(1).Infof("connection closed")
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "read avro failed")
+		}
+		if err := r.send(ctx, ch, container.Schema(), data); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *MaxscaleReader) send(ctx context.Context, ch chan<- *Record, schema *avro.Schema, data []byte) error {
+	select {
+	case ch <- &Record{
+		Table: Table{
+			Database: r.Database,
+			Name:     r.Table,
+		},
+		Format: r.Format,
+		Schema: schema,
+		Data:   data,
+	}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isSchema returns true if the line is a JSON schema and not a change record
+func isSchema(line []byte) bool {
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(line, &data); err != nil {
+		return false
+	}
+	_, hasFields := data["fields"]
+	_, hasEventType := data["event_type"]
+	return hasFields && !hasEventType
+}
+
 // REQUEST-DATA DATABASE.TABLE[.VERSION] [GTID]
 func (r *MaxscaleReader) buildRequestCommand(gtid *GTID) []byte {
 	buf := bytes.NewBufferString("REQUEST-DATA ")
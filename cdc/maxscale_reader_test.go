@@ -5,10 +5,12 @@
 package cdc_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
 	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
 	"github.com/bborbe/kafka-maxscale-cdc-connector/mocks"
 	. "github.com/onsi/ginkgo"
@@ -38,14 +40,14 @@ var _ = Describe("MaxscaleReader", func() {
 
 	It("returns error if dial fails", func() {
 		dialer.DialReturns(nil, errors.New("banana"))
-		err := reader.Read(context.Background(), nil, make(chan []byte))
+		err := reader.Read(context.Background(), nil, make(chan *cdc.Record))
 		Expect(err).NotTo(BeNil())
 		Expect(conn.WriteCallCount()).To(Equal(0))
 		Expect(conn.ReadCallCount()).To(Equal(0))
 	})
 
 	It("writes auth to connection", func() {
-		err := reader.Read(context.Background(), nil, make(chan []byte))
+		err := reader.Read(context.Background(), nil, make(chan *cdc.Record))
 		Expect(err).NotTo(BeNil())
 		Expect(conn.WriteCallCount()).To(Equal(2))
 		Expect(conn.ReadCallCount()).To(Equal(1))
@@ -55,7 +57,7 @@ var _ = Describe("MaxscaleReader", func() {
 
 	It("returns error if writes auth failed", func() {
 		conn.WriteReturns(0, errors.New("write banana"))
-		err := reader.Read(context.Background(), nil, make(chan []byte))
+		err := reader.Read(context.Background(), nil, make(chan *cdc.Record))
 		Expect(err).NotTo(BeNil())
 		Expect(conn.WriteCallCount()).To(Equal(1))
 		Expect(conn.ReadCallCount()).To(Equal(0))
@@ -63,7 +65,7 @@ var _ = Describe("MaxscaleReader", func() {
 
 	It("returns error if read failed", func() {
 		conn.ReadReturns(0, errors.New("read banana"))
-		err := reader.Read(context.Background(), nil, make(chan []byte))
+		err := reader.Read(context.Background(), nil, make(chan *cdc.Record))
 		Expect(err).NotTo(BeNil())
 		Expect(conn.WriteCallCount()).To(Equal(2))
 		Expect(conn.ReadCallCount()).To(Equal(1))
@@ -76,7 +78,7 @@ var _ = Describe("MaxscaleReader", func() {
 			n := copy(bytes[:], "ERR banana")
 			return n, nil
 		}
-		err := reader.Read(context.Background(), nil, make(chan []byte))
+		err := reader.Read(context.Background(), nil, make(chan *cdc.Record))
 		Expect(err).NotTo(BeNil())
 		Expect(conn.WriteCallCount()).To(Equal(2))
 		Expect(conn.ReadCallCount()).To(Equal(1))
@@ -94,7 +96,7 @@ var _ = Describe("MaxscaleReader", func() {
 			}
 			return 0, errors.New("read banana")
 		}
-		err := reader.Read(context.Background(), nil, make(chan []byte))
+		err := reader.Read(context.Background(), nil, make(chan *cdc.Record))
 		Expect(err).NotTo(BeNil())
 		Expect(conn.WriteCallCount()).To(Equal(3))
 		Expect(string(conn.WriteArgsForCall(0))).To(Equal("636463757365723a"))
@@ -108,7 +110,7 @@ var _ = Describe("MaxscaleReader", func() {
 			return n, nil
 		}
 		conn.WriteReturnsOnCall(2, 0, errors.New("write banana"))
-		err := reader.Read(context.Background(), nil, make(chan []byte))
+		err := reader.Read(context.Background(), nil, make(chan *cdc.Record))
 		Expect(err).NotTo(BeNil())
 		Expect(conn.WriteCallCount()).To(Equal(3))
 		Expect(string(conn.WriteArgsForCall(0))).To(Equal("636463757365723a"))
@@ -130,7 +132,7 @@ var _ = Describe("MaxscaleReader", func() {
 			}
 			return 0, errors.New("read banana")
 		}
-		err := reader.Read(context.Background(), nil, make(chan []byte))
+		err := reader.Read(context.Background(), nil, make(chan *cdc.Record))
 		Expect(err).NotTo(BeNil())
 		Expect(conn.ReadCallCount()).To(Equal(2))
 		Expect(conn.WriteCallCount()).To(Equal(3))
@@ -149,7 +151,7 @@ var _ = Describe("MaxscaleReader", func() {
 			}
 			return 0, errors.New("read banana")
 		}
-		err := reader.Read(context.Background(), nil, make(chan []byte))
+		err := reader.Read(context.Background(), nil, make(chan *cdc.Record))
 		Expect(err).NotTo(BeNil())
 		Expect(conn.ReadCallCount()).To(Equal(3))
 		Expect(conn.WriteCallCount()).To(Equal(4))
@@ -172,7 +174,7 @@ var _ = Describe("MaxscaleReader", func() {
 		}
 		gtid, err := cdc.ParseGTID("0-11-345")
 		Expect(err).To(BeNil())
-		err = reader.Read(context.Background(), gtid, make(chan []byte))
+		err = reader.Read(context.Background(), gtid, make(chan *cdc.Record))
 		Expect(err).NotTo(BeNil())
 		Expect(conn.ReadCallCount()).To(Equal(3))
 		Expect(conn.WriteCallCount()).To(Equal(4))
@@ -196,7 +198,7 @@ var _ = Describe("MaxscaleReader", func() {
 			}
 			return 0, errors.New("read banana")
 		}
-		err := reader.Read(context.Background(), nil, make(chan []byte))
+		err := reader.Read(context.Background(), nil, make(chan *cdc.Record))
 		Expect(err).NotTo(BeNil())
 		Expect(conn.ReadCallCount()).To(Equal(3))
 		Expect(conn.WriteCallCount()).To(Equal(4))
@@ -220,7 +222,7 @@ var _ = Describe("MaxscaleReader", func() {
 			}
 			return 0, io.EOF
 		}
-		ch := make(chan []byte, 10)
+		ch := make(chan *cdc.Record, 10)
 		err := reader.Read(context.Background(), nil, ch)
 		Expect(err).To(BeNil())
 		Expect(conn.ReadCallCount()).To(Equal(5))
@@ -230,7 +232,108 @@ var _ = Describe("MaxscaleReader", func() {
 		Expect(string(conn.WriteArgsForCall(2))).To(Equal("REGISTER UUID=0f672312-e02a-11e8-8c13-cf8f48795343, TYPE=JSON"))
 		Expect(string(conn.WriteArgsForCall(3))).To(Equal("REQUEST-DATA mydb.mytable"))
 		Expect(len(ch)).To(Equal(2))
-		Expect(string(<-ch)).To(Equal("line 3\n"))
-		Expect(string(<-ch)).To(Equal("line 4\n"))
+		Expect(string((<-ch).Data)).To(Equal("line 3\n"))
+		Expect(string((<-ch).Data)).To(Equal("line 4\n"))
+	})
+
+	It("uses schema line as schema of following records", func() {
+		readCounter := 0
+		conn.ReadStub = func(bytes []byte) (int, error) {
+			readCounter++
+			switch readCounter {
+			case 1, 2:
+				return copy(bytes[:], "OK\n"), nil
+			case 3:
+				return copy(bytes[:], `{"namespace": "MaxScaleChangeDataSchema.avro", "type": "record", "name": "ChangeRecord", "fields": [{"name": "domain", "type": "int"}]}`+"\n"), nil
+			case 4:
+				return copy(bytes[:], `{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "timestamp": 1541348151, "event_type": "insert", "id": 4}`+"\n"), nil
+			}
+			return 0, io.EOF
+		}
+		ch := make(chan *cdc.Record, 10)
+		err := reader.Read(context.Background(), nil, ch)
+		Expect(err).To(BeNil())
+		Expect(len(ch)).To(Equal(1))
+		record := <-ch
+		Expect(record.Format).To(Equal("JSON"))
+		Expect(record.Table).To(Equal(cdc.Table{Database: "mydb", Name: "mytable"}))
+		Expect(record.Schema).NotTo(BeNil())
+		Expect(record.Schema.Name).To(Equal("ChangeRecord"))
+		gtid, err := record.GTID()
+		Expect(err).To(BeNil())
+		Expect(gtid.String()).To(Equal("0-1-58"))
+	})
+
+	Context("AVRO", func() {
+		var schema *avro.Schema
+
+		BeforeEach(func() {
+			reader.Format = "AVRO"
+			var err error
+			schema, err = avro.ParseSchema([]byte(`{"type":"record","name":"ChangeRecord","fields":[
+				{"name":"domain","type":"int"},
+				{"name":"server_id","type":"int"},
+				{"name":"sequence","type":"int"},
+				{"name":"event_number","type":"int"},
+				{"name":"name","type":"string"}
+			]}`))
+			Expect(err).To(BeNil())
+		})
+
+		serve := func(stream []byte) {
+			readCounter := 0
+			content := bytes.NewReader(stream)
+			conn.ReadStub = func(buf []byte) (int, error) {
+				readCounter++
+				if readCounter <= 2 {
+					return copy(buf[:], "OK\n"), nil
+				}
+				return content.Read(buf)
+			}
+		}
+
+		It("sends one record per avro record", func() {
+			buf := &bytes.Buffer{}
+			writer := avro.NewContainerWriter(buf, schema)
+			Expect(writer.WriteBlock(
+				map[string]interface{}{"domain": 0, "server_id": 1, "sequence": 10, "event_number": 1, "name": "line\nbreak"},
+				map[string]interface{}{"domain": 0, "server_id": 1, "sequence": 10, "event_number": 2, "name": "b"},
+			)).To(BeNil())
+			Expect(writer.WriteBlock(
+				map[string]interface{}{"domain": 0, "server_id": 1, "sequence": 11, "event_number": 1, "name": "c"},
+			)).To(BeNil())
+			serve(buf.Bytes())
+
+			ch := make(chan *cdc.Record, 10)
+			err := reader.Read(context.Background(), nil, ch)
+			Expect(err).To(BeNil())
+			Expect(string(conn.WriteArgsForCall(2))).To(Equal("REGISTER UUID=0f672312-e02a-11e8-8c13-cf8f48795343, TYPE=AVRO"))
+			Expect(len(ch)).To(Equal(3))
+			record := <-ch
+			Expect(record.Format).To(Equal("AVRO"))
+			Expect(record.Schema.String()).To(Equal(schema.String()))
+			values, err := record.Values()
+			Expect(err).To(BeNil())
+			Expect(values).To(HaveKeyWithValue("name", "line\nbreak"))
+			gtid, err := record.GTID()
+			Expect(err).To(BeNil())
+			Expect(gtid.String()).To(Equal("0-1-10"))
+			<-ch
+			gtid, err = (<-ch).GTID()
+			Expect(err).To(BeNil())
+			Expect(gtid.String()).To(Equal("0-1-11"))
+		})
+
+		It("returns error if request returns ERR", func() {
+			serve([]byte("ERR table not found\n"))
+			err := reader.Read(context.Background(), nil, make(chan *cdc.Record, 10))
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("returns error on corrupt stream", func() {
+			serve([]byte("banana"))
+			err := reader.Read(context.Background(), nil, make(chan *cdc.Record, 10))
+			Expect(err).NotTo(BeNil())
+		})
 	})
 })
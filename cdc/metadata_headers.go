@@ -0,0 +1,52 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"strconv"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+)
+
+// MetadataHeaders adds the change metadata of the record as headers,
+// so consumers can route and filter without decoding the value
+type MetadataHeaders struct {
+	// UUID of the connector
+	UUID string
+	// Host of Maxscale
+	Host string
+}
+
+// Headers returns database, table, version, event_type, gtid, event_number, uuid and host.
+// Version and event_number are missing if they are unknown.
+func (m *MetadataHeaders) Headers(topic string, record *Record) ([]sarama.RecordHeader, error) {
+	values, err := record.Values()
+	if err != nil {
+		return nil, errors.Wrap(err, "get values failed")
+	}
+	gtid, err := GTIDFromValues(values)
+	if err != nil {
+		return nil, err
+	}
+	headers := []sarama.RecordHeader{
+		header("database", record.Table.Database),
+		header("table", record.Table.Name),
+	}
+	if record.Version > 0 {
+		headers = append(headers, header("version", strconv.Itoa(record.Version)))
+	}
+	if eventType, ok := values["event_type"].(string); ok {
+		headers = append(headers, header("event_type", eventType))
+	}
+	headers = append(headers, header("gtid", gtid.String()))
+	if number := eventNumber(values); number > 0 {
+		headers = append(headers, header("event_number", strconv.FormatUint(number, 10)))
+	}
+	return append(headers,
+		header("uuid", m.UUID),
+		header("host", m.Host),
+	), nil
+}
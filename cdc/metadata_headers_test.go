@@ -0,0 +1,57 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MetadataHeaders", func() {
+	var metadataHeaders *cdc.MetadataHeaders
+
+	BeforeEach(func() {
+		metadataHeaders = &cdc.MetadataHeaders{
+			UUID: "8b7b1f3c-0b5e-4c55-a3b1-4b2d6a5e9f10",
+			Host: "maxscale.example.com",
+		}
+	})
+
+	It("returns the change metadata", func() {
+		record := jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2, "event_type": "insert", "id": 4}`)
+		record.Version = 3
+		headers, err := metadataHeaders.Headers("mytopic", record)
+		Expect(err).To(BeNil())
+		Expect(headerMap(headers)).To(Equal(map[string]string{
+			"database":     "mydb",
+			"table":        "mytable",
+			"version":      "3",
+			"event_type":   "insert",
+			"gtid":         "0-1-58",
+			"event_number": "2",
+			"uuid":         "8b7b1f3c-0b5e-4c55-a3b1-4b2d6a5e9f10",
+			"host":         "maxscale.example.com",
+		}))
+	})
+
+	It("omits unknown version and event number", func() {
+		headers, err := metadataHeaders.Headers("mytopic", jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "delete"}`))
+		Expect(err).To(BeNil())
+		Expect(headerMap(headers)).NotTo(HaveKey("version"))
+		Expect(headerMap(headers)).NotTo(HaveKey("event_number"))
+	})
+
+	It("returns headers of avro records", func() {
+		headers, err := metadataHeaders.Headers("mytopic", avroRecord(map[string]interface{}{"domain": 0, "server_id": 1, "sequence": 58}))
+		Expect(err).To(BeNil())
+		Expect(headerMap(headers)).To(HaveKeyWithValue("gtid", "0-1-58"))
+	})
+
+	It("returns error without gtid", func() {
+		_, err := metadataHeaders.Headers("mytopic", jsonRecord(`{"event_type": "insert"}`))
+		Expect(err).NotTo(BeNil())
+	})
+})
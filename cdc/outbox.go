@@ -0,0 +1,102 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+)
+
+// Columns of the outbox table
+const (
+	OutboxAggregateType = "aggregate_type"
+	OutboxAggregateID   = "aggregate_id"
+	OutboxPayload       = "payload"
+	OutboxHeaders       = "headers"
+)
+
+// DefaultOutboxTopic routes outbox events by their aggregate type
+const DefaultOutboxTopic = "outbox.event.{" + OutboxAggregateType + "}"
+
+// OutboxEncoder sends the payload column of outbox records as value and the headers column as headers.
+// Deletes have a null value, so they are tombstones for the key of the aggregate.
+type OutboxEncoder struct{}
+
+// Encode returns the payload of inserts and null for deletes
+func (o *OutboxEncoder) Encode(topic string, record *Record) ([]byte, error) {
+	values, err := record.Values()
+	if err != nil {
+		return nil, errors.Wrap(err, "get values failed")
+	}
+	if values["event_type"] == "delete" {
+		return nil, nil
+	}
+	return outboxBytes(values[OutboxPayload]), nil
+}
+
+// Headers returns the entries of the JSON object in the headers column, values that are no strings are written as JSON
+func (o *OutboxEncoder) Headers(topic string, record *Record) ([]sarama.RecordHeader, error) {
+	values, err := record.Values()
+	if err != nil {
+		return nil, errors.Wrap(err, "get values failed")
+	}
+	data := outboxBytes(values[OutboxHeaders])
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, errors.Wrapf(err, "decode %s of %s failed", OutboxHeaders, record.Table)
+	}
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var result []sarama.RecordHeader
+	for _, key := range keys {
+		var value string
+		if err := json.Unmarshal(entries[key], &value); err != nil {
+			value = string(entries[key])
+		}
+		result = append(result, header(key, value))
+	}
+	return result, nil
+}
+
+// OutboxKeyEncoder uses the aggregate_id column as key, so all events of an aggregate keep their order
+type OutboxKeyEncoder struct{}
+
+// Encode returns the aggregate id
+func (o *OutboxKeyEncoder) Encode(topic string, record *Record) ([]byte, error) {
+	values, err := record.Values()
+	if err != nil {
+		return nil, errors.Wrap(err, "get values failed")
+	}
+	key := outboxBytes(values[OutboxAggregateID])
+	if key == nil {
+		return nil, errors.Errorf("%s of %s missing", OutboxAggregateID, record.Table)
+	}
+	return key, nil
+}
+
+// outboxBytes returns strings and bytes unchanged and other values formatted, nil stays nil
+func outboxBytes(value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/Shopify/sarama"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func outboxRecord(data string) *cdc.Record {
+	record := jsonRecord(data)
+	record.Table = cdc.Table{Database: "shop", Name: "outbox"}
+	return record
+}
+
+var _ = Describe("OutboxEncoder", func() {
+	encoder := &cdc.OutboxEncoder{}
+	insert := `{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "event_type": "insert", "aggregate_type": "order", "aggregate_id": "4711", "payload": "{\"id\": 4711, \"total\": 9.5}", "headers": "{\"type\": \"OrderCreated\", \"version\": 2}"}`
+
+	It("returns the payload as value", func() {
+		value, err := encoder.Encode("outbox.event.order", outboxRecord(insert))
+		Expect(err).To(BeNil())
+		Expect(string(value)).To(Equal(`{"id": 4711, "total": 9.5}`))
+	})
+
+	It("returns null for deletes", func() {
+		value, err := encoder.Encode("outbox.event.order", outboxRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "delete", "aggregate_id": "4711", "payload": "{}"}`))
+		Expect(err).To(BeNil())
+		Expect(value).To(BeNil())
+	})
+
+	It("returns the headers column as headers", func() {
+		headers, err := encoder.Headers("outbox.event.order", outboxRecord(insert))
+		Expect(err).To(BeNil())
+		Expect(headers).To(Equal([]sarama.RecordHeader{
+			{Key: []byte("type"), Value: []byte("OrderCreated")},
+			{Key: []byte("version"), Value: []byte("2")},
+		}))
+	})
+
+	It("returns no headers for null", func() {
+		headers, err := encoder.Headers("outbox.event.order", outboxRecord(`{"event_type": "insert", "headers": null}`))
+		Expect(err).To(BeNil())
+		Expect(headers).To(BeEmpty())
+	})
+
+	It("returns error for invalid headers", func() {
+		_, err := encoder.Headers("outbox.event.order", outboxRecord(`{"event_type": "insert", "headers": "banana"}`))
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns the aggregate id as key", func() {
+		key, err := (&cdc.OutboxKeyEncoder{}).Encode("outbox.event.order", outboxRecord(insert))
+		Expect(err).To(BeNil())
+		Expect(string(key)).To(Equal("4711"))
+	})
+
+	It("returns error without aggregate id", func() {
+		_, err := (&cdc.OutboxKeyEncoder{}).Encode("outbox.event.order", outboxRecord(`{"event_type": "insert"}`))
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("KafkaSender with outbox encoders", func() {
+	var producer *mocks.SyncProducer
+	var sender *cdc.KafkaSender
+	var dataDir string
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = ioutil.TempDir("", "kafka-sender")
+		Expect(err).To(BeNil())
+		producer = &mocks.SyncProducer{}
+		sender = &cdc.KafkaSender{
+			Producer:       producer,
+			TopicRouter:    &cdc.TopicTemplate{Template: cdc.DefaultOutboxTopic},
+			GTIDStore:      &cdc.GTIDStore{DataDir: dataDir},
+			ValueEncoder:   &cdc.OutboxEncoder{},
+			KeyEncoder:     &cdc.OutboxKeyEncoder{},
+			HeaderEncoders: []cdc.HeaderEncoder{&cdc.OutboxEncoder{}},
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dataDir)
+	})
+
+	It("routes by aggregate type and keys by aggregate id", func() {
+		err := sendRecords(sender, outboxRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "insert", "aggregate_type": "order", "aggregate_id": 4711, "payload": "{}"}`))
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(1))
+		msg := producer.SendMessageArgsForCall(0)
+		Expect(msg.Topic).To(Equal("outbox.event.order"))
+		Expect(msg.Key).To(Equal(sarama.ByteEncoder("4711")))
+		Expect(msg.Value).To(Equal(sarama.ByteEncoder("{}")))
+	})
+
+	It("sends deletes as tombstone", func() {
+		err := sendRecords(sender, outboxRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "delete", "aggregate_type": "order", "aggregate_id": 4711, "payload": "{}"}`))
+		Expect(err).To(BeNil())
+		Expect(producer.SendMessageCallCount()).To(Equal(1))
+		msg := producer.SendMessageArgsForCall(0)
+		Expect(msg.Key).To(Equal(sarama.ByteEncoder("4711")))
+		Expect(msg.Value).To(BeNil())
+	})
+})
@@ -0,0 +1,28 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"github.com/golang/glog"
+)
+
+// PositionFilter drops records of transactions the position already passed.
+// Resuming a position with several domains replays transactions of the other domains that were sent before.
+type PositionFilter struct {
+	Position GTIDPosition
+}
+
+// Process drops the record if its domain is already past its GTID
+func (p *PositionFilter) Process(record *Record) ([]*Record, error) {
+	gtid, err := record.GTID()
+	if err != nil {
+		return []*Record{record}, nil
+	}
+	if p.Position.Passed(gtid) {
+		glog.V(3).Infof("drop record of %s from %s, position is %s", record.Table, gtid, p.Position)
+		return nil, nil
+	}
+	return []*Record{record}, nil
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PositionFilter", func() {
+	var filter *cdc.PositionFilter
+
+	BeforeEach(func() {
+		position, err := cdc.ParseGTIDPosition("0-1-58,1-2-10")
+		Expect(err).To(BeNil())
+		filter = &cdc.PositionFilter{Position: position}
+	})
+
+	It("drops records of passed transactions", func() {
+		records, err := filter.Process(jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 57}`))
+		Expect(err).To(BeNil())
+		Expect(records).To(BeEmpty())
+	})
+
+	It("keeps records of the position and later transactions", func() {
+		for _, data := range []string{
+			`{"domain": 0, "server_id": 1, "sequence": 58}`,
+			`{"domain": 1, "server_id": 2, "sequence": 11}`,
+			`{"domain": 2, "server_id": 3, "sequence": 1}`,
+		} {
+			records, err := filter.Process(jsonRecord(data))
+			Expect(err).To(BeNil())
+			Expect(records).To(HaveLen(1))
+		}
+	})
+
+	It("keeps records without gtid", func() {
+		records, err := filter.Process(jsonRecord(`banana`))
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+	})
+})
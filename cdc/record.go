@@ -0,0 +1,227 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	"github.com/pkg/errors"
+)
+
+// Record is a single change record of a table
+type Record struct {
+	Table  Table
+	Format string // JSON or AVRO
+	// Schema of the table the record was written with
+	Schema *avro.Schema
+	// Version of the schema in the schema history, 0 if unknown
+	Version int
+	// Before is the update_before record of an update_after record, set by the UpdateMerger
+	Before *Record
+	// Data of the record, a JSON line or the Avro binary encoded record without container
+	Data []byte
+	// Dropped records were removed by a filter, they are not sent but advance the checkpoint
+	Dropped bool
+}
+
+// dropped returns a record that is not sent but advances the checkpoint past the given record
+func dropped(record *Record) *Record {
+	return &Record{
+		Table:   record.Table,
+		Format:  record.Format,
+		Schema:  record.Schema,
+		Version: record.Version,
+		Data:    record.Data,
+		Dropped: true,
+	}
+}
+
+// Values returns all decoded fields of the record
+func (r *Record) Values() (map[string]interface{}, error) {
+	switch r.Format {
+	case "JSON":
+		var values map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewBuffer(r.Data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			return nil, errors.Wrap(err, "decode json failed")
+		}
+		return values, nil
+	case "AVRO":
+		if r.Schema == nil {
+			return nil, errors.New("schema missing")
+		}
+		value, err := avro.Decode(r.Schema, bytes.NewReader(r.Data))
+		if err != nil {
+			return nil, errors.Wrap(err, "decode avro failed")
+		}
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("expected record but got %T", value)
+		}
+		return values, nil
+	default:
+		return nil, errors.Errorf("unsupported format %s", r.Format)
+	}
+}
+
+// SetValues replaces the data of the record with the given values encoded in the format of the record
+func (r *Record) SetValues(values map[string]interface{}) error {
+	switch r.Format {
+	case "JSON":
+		data, err := encodeJSON(r.Schema, values)
+		if err != nil {
+			return errors.Wrap(err, "encode json failed")
+		}
+		r.Data = data
+		return nil
+	case "AVRO":
+		if r.Schema == nil {
+			return errors.New("schema missing")
+		}
+		buf := &bytes.Buffer{}
+		if err := avro.Encode(r.Schema, buf, values); err != nil {
+			return errors.Wrap(err, "encode avro failed")
+		}
+		r.Data = buf.Bytes()
+		return nil
+	default:
+		return errors.Errorf("unsupported format %s", r.Format)
+	}
+}
+
+// GTID returns the GTID of the transaction the record belongs to
+func (r *Record) GTID() (*GTID, error) {
+	values, err := r.Values()
+	if err != nil {
+		return nil, err
+	}
+	return GTIDFromValues(values)
+}
+
+// GTIDFromValues returns the GTID stored in the domain, server_id and sequence fields
+func GTIDFromValues(values map[string]interface{}) (*GTID, error) {
+	domain, err := toUint64(values["domain"])
+	if err != nil {
+		return nil, errors.Wrap(err, "parse domain failed")
+	}
+	serverId, err := toUint64(values["server_id"])
+	if err != nil {
+		return nil, errors.Wrap(err, "parse server_id failed")
+	}
+	sequence, err := toUint64(values["sequence"])
+	if err != nil {
+		return nil, errors.Wrap(err, "parse sequence failed")
+	}
+	return &GTID{
+		Domain:   uint32(domain),
+		ServerId: uint32(serverId),
+		Sequence: sequence,
+	}, nil
+}
+
+// eventNumber returns the number of the event within its transaction or 0 if unknown
+func eventNumber(values map[string]interface{}) uint64 {
+	number, err := toUint64(values["event_number"])
+	if err != nil {
+		return 0
+	}
+	return number
+}
+
+// eventTime returns the time of the event from the timestamp in seconds
+func eventTime(values map[string]interface{}) (time.Time, error) {
+	timestamp, err := toUint64(values["timestamp"])
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "parse timestamp failed")
+	}
+	return time.Unix(int64(timestamp), 0), nil
+}
+
+func toUint64(value interface{}) (uint64, error) {
+	switch v := value.(type) {
+	case int32:
+		return uint64(v), nil
+	case int64:
+		return uint64(v), nil
+	case int:
+		return uint64(v), nil
+	case float64:
+		return uint64(v), nil
+	case json.Number:
+		return strconv.ParseUint(string(v), 10, 64)
+	default:
+		return 0, errors.Errorf("expected number but got %T", value)
+	}
+}
+
+// encodeJSON writes the values as JSON line like Maxscale does.
+func encodeJSON(schema *avro.Schema, values map[string]interface{}) ([]byte, error) {
+	data, err := encodeJSONObject(schemaKeys(schema, values), values)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// schemaKeys returns the keys of values ordered as in the schema, unknown fields follow sorted by name
+func schemaKeys(schema *avro.Schema, values map[string]interface{}) []string {
+	var keys []string
+	known := make(map[string]bool)
+	if schema != nil {
+		for _, field := range schema.Fields {
+			if _, ok := values[field.Name]; ok {
+				keys = append(keys, field.Name)
+				known[field.Name] = true
+			}
+		}
+	}
+	var unknown []string
+	for key := range values {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return append(keys, unknown...)
+}
+
+// encodeJSONObject writes the given keys of values as JSON object in the order of keys
+func encodeJSONObject(keys []string, values map[string]interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("{")
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteString(": ")
+		// nested objects encoded by encodeJSONObject keep their formatting
+		if raw, ok := values[key].(json.RawMessage); ok {
+			buf.Write(raw)
+			continue
+		}
+		value := values[key]
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		v, err := json.Marshal(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encode field %s failed", key)
+		}
+		buf.Write(v)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
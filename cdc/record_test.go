@@ -0,0 +1,84 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const gtidSchema = `{"type":"record","name":"ChangeRecord","namespace":"MaxScaleChangeDataSchema.avro","fields":[
+	{"name":"domain","type":"int"},
+	{"name":"server_id","type":"int"},
+	{"name":"sequence","type":"long"}
+]}`
+
+func avroRecord(values map[string]interface{}) *cdc.Record {
+	schema, err := avro.ParseSchema([]byte(gtidSchema))
+	Expect(err).To(BeNil())
+	buf := &bytes.Buffer{}
+	Expect(avro.Encode(schema, buf, values)).To(BeNil())
+	return &cdc.Record{
+		Table:  cdc.Table{Database: "mydb", Name: "mytable"},
+		Format: "AVRO",
+		Schema: schema,
+		Data:   buf.Bytes(),
+	}
+}
+
+var _ = Describe("Record", func() {
+
+	It("decodes json values", func() {
+		record := &cdc.Record{
+			Format: "JSON",
+			Data:   []byte(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "insert", "name": "Hello"}` + "\n"),
+		}
+		values, err := record.Values()
+		Expect(err).To(BeNil())
+		Expect(values).To(HaveKeyWithValue("sequence", json.Number("58")))
+		Expect(values).To(HaveKeyWithValue("name", "Hello"))
+		gtid, err := record.GTID()
+		Expect(err).To(BeNil())
+		Expect(gtid.String()).To(Equal("0-1-58"))
+	})
+
+	It("decodes avro values", func() {
+		record := avroRecord(map[string]interface{}{"domain": 1, "server_id": 2, "sequence": 3})
+		gtid, err := record.GTID()
+		Expect(err).To(BeNil())
+		Expect(gtid.String()).To(Equal("1-2-3"))
+	})
+
+	It("returns error for avro without schema", func() {
+		record := &cdc.Record{
+			Format: "AVRO",
+			Data:   []byte{0},
+		}
+		_, err := record.Values()
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error if gtid fields are missing", func() {
+		record := &cdc.Record{
+			Format: "JSON",
+			Data:   []byte(`{"name": "Hello"}`),
+		}
+		_, err := record.GTID()
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error for unsupported format", func() {
+		record := &cdc.Record{
+			Format: "banana",
+		}
+		_, err := record.Values()
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -6,31 +6,38 @@ package cdc
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
 )
 
-// RetryReader store the gtid of the last message and resume there on failure
+// retryDelay is the time to wait before a failed stream is restarted
+const retryDelay = 10 * time.Second
+
+// RetryReader store the gtid of the last message and resume there on failure.
+// Events of the resumed transaction that were already read are replayed, see EventDeduplicator.
 type RetryReader struct {
-	Reader        Reader
-	GTIDExtractor interface {
-		Parse(line []byte) (*GTID, error)
-	}
+	Reader Reader
 }
 
 // Read from the sub reader and retry if needed
-func (r *RetryReader) Read(ctx context.Context, gtid *GTID, outch chan<- []byte) error {
-	ch := make(chan []byte)
+func (r *RetryReader) Read(ctx context.Context, gtid *GTID, outch chan<- *Record) error {
+	var mux sync.Mutex
+	ch := make(chan *Record)
 	defer close(ch)
 	go func() {
-		for line := range ch {
-			newGtid, err := r.GTIDExtractor.Parse(line)
+		for record := range ch {
+			newGtid, err := record.GTID()
 			if err != nil {
 				glog.V(2).Infof("%v", err)
 			}
-			outch <- line
-			gtid = newGtid
+			outch <- record
+			if newGtid != nil {
+				mux.Lock()
+				gtid = newGtid
+				mux.Unlock()
+			}
 		}
 	}()
 
@@ -39,11 +46,14 @@ func (r *RetryReader) Read(ctx context.Context, gtid *GTID, outch chan<- []byte)
 		case <-ctx.Done():
 			return nil
 		default:
-			if err := r.Reader.Read(ctx, gtid, ch); err != nil {
+			mux.Lock()
+			resume := gtid
+			mux.Unlock()
+			if err := r.Reader.Read(ctx, resume, ch); err != nil {
 				glog.Warningf("read failed: %v", err)
 			}
 			glog.V(3).Infof("reader closed => restart")
-			time.Sleep(10 * time.Second)
+			time.Sleep(retryDelay)
 		}
 	}
 }
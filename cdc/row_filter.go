@@ -0,0 +1,135 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	rowFilterMatched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cdc",
+		Subsystem: "row_filter",
+		Name:      "matched_total",
+		Help:      "Number of records matching the row filter",
+	}, []string{"database", "table"})
+	rowFilterDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cdc",
+		Subsystem: "row_filter",
+		Name:      "dropped_total",
+		Help:      "Number of records dropped by the row filter",
+	}, []string{"database", "table"})
+)
+
+func init() {
+	prometheus.MustRegister(rowFilterMatched, rowFilterDropped)
+}
+
+// ParseRowFilters parses a semicolon separated list of PATTERN=EXPRESSION,
+// e.g. shop.orders=tenant_id == 42 && status != "draft"
+func ParseRowFilters(filters string) (map[string]*Expression, error) {
+	result := make(map[string]*Expression)
+	for _, entry := range splitUnquoted(filters, ';') {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, errors.Errorf("parse row filter %s failed", entry)
+		}
+		pattern := strings.TrimSpace(parts[0])
+		if err := (&TableMatcher{Include: []string{pattern}}).Validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := result[pattern]; ok {
+			return nil, errors.Errorf("duplicate row filter for %s", pattern)
+		}
+		expression, err := ParseExpression(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		result[pattern] = expression
+	}
+	return result, nil
+}
+
+// splitUnquoted splits at the separator outside of string literals
+func splitUnquoted(value string, separator rune) []string {
+	var result []string
+	var quote rune
+	escaped := false
+	start := 0
+	for i, r := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0 && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == separator:
+			result = append(result, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(result, value[start:])
+}
+
+// RowFilter forwards only records matching all expressions.
+// Both images of an update are evaluated together, the update_after is the row and update_before
+// the before image, so both records are forwarded or dropped.
+type RowFilter struct {
+	Expressions []*Expression
+
+	before *Record
+}
+
+// Process holds back update_before records until the matching update_after arrived
+func (r *RowFilter) Process(record *Record) ([]*Record, error) {
+	values, err := record.Values()
+	if err != nil {
+		return []*Record{record}, nil
+	}
+	var result []*Record
+	if r.before != nil {
+		before := r.before
+		r.before = nil
+		beforeValues, _ := before.Values()
+		if values["event_type"] == "update_after" && isBeforeImage(before, values) {
+			return r.filter(record.Table, values, beforeValues, before, record), nil
+		}
+		result = r.filter(before.Table, beforeValues, beforeValues, before)
+	}
+	if values["event_type"] == "update_before" {
+		r.before = record
+		return result, nil
+	}
+	return append(result, r.filter(record.Table, values, nil, record)...), nil
+}
+
+// filter returns the records if the row matches all expressions, dropped records otherwise
+func (r *RowFilter) filter(table Table, row map[string]interface{}, before map[string]interface{}, records ...*Record) []*Record {
+	for _, expression := range r.Expressions {
+		if !expression.Match(row, before) {
+			glog.V(3).Infof("drop %d records of %s not matching %s", len(records), table, expression)
+			rowFilterDropped.WithLabelValues(table.Database, table.Name).Add(float64(len(records)))
+			var result []*Record
+			for _, record := range records {
+				result = append(result, dropped(record))
+			}
+			return result
+		}
+	}
+	rowFilterMatched.WithLabelValues(table.Database, table.Name).Add(float64(len(records)))
+	return records
+}
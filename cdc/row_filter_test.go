@@ -0,0 +1,144 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// counterValue returns the value of the counter with the given labels from the default registry
+func counterValue(name string, database string, table string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	Expect(err).To(BeNil())
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["database"] == database && labels["table"] == table {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+var _ = Describe("RowFilter", func() {
+	var filter *cdc.RowFilter
+
+	BeforeEach(func() {
+		expression, err := cdc.ParseExpression(`tenant_id == 42 && status != "draft"`)
+		Expect(err).To(BeNil())
+		filter = &cdc.RowFilter{
+			Expressions: []*cdc.Expression{expression},
+		}
+	})
+
+	process := func(data string) []*cdc.Record {
+		records, err := filter.Process(jsonRecord(data))
+		Expect(err).To(BeNil())
+		return records
+	}
+
+	dropped := func(records []*cdc.Record) int {
+		count := 0
+		for _, record := range records {
+			if record.Dropped {
+				count++
+			}
+		}
+		return count
+	}
+
+	It("forwards matching rows", func() {
+		records := process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "event_type": "insert", "tenant_id": 42, "status": "open"}`)
+		Expect(records).To(HaveLen(1))
+		Expect(dropped(records)).To(Equal(0))
+	})
+
+	It("drops other rows", func() {
+		records := process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "event_type": "insert", "tenant_id": 42, "status": "draft"}`)
+		Expect(records).To(HaveLen(1))
+		Expect(dropped(records)).To(Equal(1))
+		records = process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2, "event_type": "insert", "tenant_id": 7, "status": "open"}`)
+		Expect(records).To(HaveLen(1))
+		Expect(dropped(records)).To(Equal(1))
+	})
+
+	It("forwards both images of a matching update", func() {
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "event_type": "update_before", "tenant_id": 42, "status": "draft"}`)).To(BeEmpty())
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2, "event_type": "update_after", "tenant_id": 42, "status": "open"}`)).To(HaveLen(2))
+	})
+
+	It("drops both images of an update not matching", func() {
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "event_type": "update_before", "tenant_id": 42, "status": "open"}`)).To(BeEmpty())
+		records := process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2, "event_type": "update_after", "tenant_id": 42, "status": "draft"}`)
+		Expect(records).To(HaveLen(2))
+		Expect(dropped(records)).To(Equal(2))
+	})
+
+	It("evaluates the before image of updates", func() {
+		expression, err := cdc.ParseExpression(`before.status == "draft" && status == "published"`)
+		Expect(err).To(BeNil())
+		filter.Expressions = []*cdc.Expression{expression}
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "event_type": "update_before", "status": "draft"}`)).To(BeEmpty())
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2, "event_type": "update_after", "status": "published"}`)).To(HaveLen(2))
+		Expect(dropped(process(`{"domain": 0, "server_id": 1, "sequence": 59, "event_number": 1, "event_type": "insert", "status": "published"}`))).To(Equal(1))
+	})
+
+	It("evaluates an update_before without update_after as row", func() {
+		Expect(process(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "event_type": "update_before", "tenant_id": 42, "status": "open"}`)).To(BeEmpty())
+		records := process(`{"domain": 0, "server_id": 1, "sequence": 59, "event_number": 1, "event_type": "insert", "tenant_id": 42, "status": "open"}`)
+		Expect(records).To(HaveLen(2))
+		values, err := records[0].Values()
+		Expect(err).To(BeNil())
+		Expect(values).To(HaveKeyWithValue("event_type", "update_before"))
+	})
+
+	It("counts matched and dropped records", func() {
+		record := jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "event_type": "insert", "tenant_id": 42, "status": "open"}`)
+		record.Table = cdc.Table{Database: "metrics", Name: "orders"}
+		_, err := filter.Process(record)
+		Expect(err).To(BeNil())
+		record = jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2, "event_type": "insert", "tenant_id": 7, "status": "open"}`)
+		record.Table = cdc.Table{Database: "metrics", Name: "orders"}
+		_, err = filter.Process(record)
+		Expect(err).To(BeNil())
+		Expect(counterValue("cdc_row_filter_matched_total", "metrics", "orders")).To(Equal(float64(1)))
+		Expect(counterValue("cdc_row_filter_dropped_total", "metrics", "orders")).To(Equal(float64(1)))
+	})
+})
+
+var _ = Describe("ParseRowFilters", func() {
+	It("parses filters by table pattern", func() {
+		filters, err := cdc.ParseRowFilters(`shop.orders=tenant_id == 42 && status != "draft"; shop.items_*=tenant_id == 42`)
+		Expect(err).To(BeNil())
+		Expect(filters).To(HaveLen(2))
+		Expect(filters["shop.orders"].String()).To(Equal(`tenant_id == 42 && status != "draft"`))
+	})
+
+	It("ignores semicolons in string literals", func() {
+		filters, err := cdc.ParseRowFilters(`shop.orders=name == 'a;b' || note == "c\";d";shop.items=id == 1`)
+		Expect(err).To(BeNil())
+		Expect(filters).To(HaveLen(2))
+		Expect(filters["shop.orders"].String()).To(Equal(`name == 'a;b' || note == "c\";d"`))
+		Expect(filters["shop.orders"].Match(map[string]interface{}{"name": "a;b"}, nil)).To(BeTrue())
+		Expect(filters["shop.items"].String()).To(Equal(`id == 1`))
+	})
+
+	It("returns error for invalid filters", func() {
+		for _, filters := range []string{`shop.orders`, `=a == 1`, `shop.orders=a ==`, `[=a == 1`, `shop.orders=a == 1;shop.orders=b == 1`} {
+			_, err := cdc.ParseRowFilters(filters)
+			Expect(err).NotTo(BeNil(), filters)
+		}
+	})
+})
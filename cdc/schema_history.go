@@ -0,0 +1,266 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// TableSchema is a version of the table schema Maxscale sends at the start of every stream
+type TableSchema struct {
+	Database string   `json:"database"`
+	Table    string   `json:"table"`
+	Version  int      `json:"version"`
+	Columns  []Column `json:"columns"`
+	// Schema is the Avro schema sent by Maxscale
+	Schema json.RawMessage `json:"schema"`
+}
+
+// Column of a table, the metadata fields added by Maxscale are not included
+type Column struct {
+	Name string `json:"name"`
+	// Type is the Avro type, for nullable columns the type besides null
+	Type string `json:"type"`
+	// RealType and Length are the SQL type reported by Maxscale
+	RealType string `json:"real_type,omitempty"`
+	Length   int    `json:"length,omitempty"`
+	Nullable bool   `json:"nullable"`
+}
+
+// NewTableSchema returns the typed model of the given Avro record schema
+func NewTableSchema(table Table, version int, schema *avro.Schema) (*TableSchema, error) {
+	if schema.Type != avro.TypeRecord {
+		return nil, errors.Errorf("expected record schema but got %s", schema.Type)
+	}
+	raw, err := compactSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	result := &TableSchema{
+		Database: table.Database,
+		Table:    table.Name,
+		Version:  version,
+		Columns:  []Column{},
+		Schema:   raw,
+	}
+	for _, field := range schema.Fields {
+		if metadataFields[field.Name] {
+			continue
+		}
+		column := Column{
+			Name:     field.Name,
+			Type:     field.Type.Type,
+			Nullable: field.Type.Nullable(),
+		}
+		for _, t := range field.Type.Types {
+			if t.Type != avro.TypeNull {
+				column.Type = t.Type
+				break
+			}
+		}
+		if realType, ok := field.Attributes["real_type"].(string); ok {
+			column.RealType = realType
+		}
+		if length, ok := field.Attributes["length"].(float64); ok && length > 0 {
+			column.Length = int(length)
+		}
+		result.Columns = append(result.Columns, column)
+	}
+	return result, nil
+}
+
+// Key of the schema in the history topic, DATABASE.TABLE.VERSION
+func (t *TableSchema) Key() string {
+	return fmt.Sprintf("%s.%s.%d", t.Database, t.Table, t.Version)
+}
+
+// compactSchema returns the schema JSON without whitespace, so equal schemas have equal bytes
+func compactSchema(schema *avro.Schema) ([]byte, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode schema failed")
+	}
+	buf := &bytes.Buffer{}
+	if err := json.Compact(buf, data); err != nil {
+		return nil, errors.Wrap(err, "compact schema failed")
+	}
+	return buf.Bytes(), nil
+}
+
+// SchemaHistory keeps all schema versions of the tables in memory.
+// If a topic is set, new versions are published to it keyed by DATABASE.TABLE.VERSION.
+// Versions of a table are serialized by a lock per table, publishing does not block other tables.
+type SchemaHistory struct {
+	Producer SyncProducer
+	Topic    string
+
+	mux      sync.Mutex
+	versions map[Table][]*TableSchema
+	tables   map[Table]*sync.Mutex
+}
+
+// Version returns the version of the schema. A schema not known yet becomes the next version of the table.
+func (s *SchemaHistory) Version(table Table, schema *avro.Schema) (*TableSchema, error) {
+	raw, err := compactSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	lock := s.tableLock(table)
+	lock.Lock()
+	defer lock.Unlock()
+	s.mux.Lock()
+	versions := s.versions[table]
+	s.mux.Unlock()
+	for _, version := range versions {
+		if version != nil && bytes.Equal(version.Schema, raw) {
+			return version, nil
+		}
+	}
+	tableSchema, err := NewTableSchema(table, len(versions)+1, schema)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.publish(tableSchema); err != nil {
+		return nil, err
+	}
+	glog.V(1).Infof("new schema version %s", tableSchema.Key())
+	s.mux.Lock()
+	s.add(tableSchema)
+	s.mux.Unlock()
+	return tableSchema, nil
+}
+
+// tableLock returns the lock serializing new versions of the table
+func (s *SchemaHistory) tableLock(table Table) *sync.Mutex {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.tables == nil {
+		s.tables = make(map[Table]*sync.Mutex)
+	}
+	lock, ok := s.tables[table]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.tables[table] = lock
+	}
+	return lock
+}
+
+// Latest returns the latest schema version of the table or nil if none is known
+func (s *SchemaHistory) Latest(table Table) *TableSchema {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	versions := s.versions[table]
+	if len(versions) == 0 {
+		return nil
+	}
+	return versions[len(versions)-1]
+}
+
+// Load all versions published to the topic, so versions continue after a restart
+func (s *SchemaHistory) Load(client sarama.Client, timeout time.Duration) error {
+	partitions, err := client.Partitions(s.Topic)
+	if err == sarama.ErrUnknownTopicOrPartition {
+		glog.V(1).Infof("schema topic %s not found", s.Topic)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "get partitions of %s failed", s.Topic)
+	}
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return errors.Wrap(err, "create consumer failed")
+	}
+	defer consumer.Close()
+	var tableSchemas []*TableSchema
+	for _, partition := range partitions {
+		messages, err := readMessages(client, consumer, s.Topic, partition, 0, timeout)
+		if err != nil {
+			return err
+		}
+		for _, msg := range messages {
+			var tableSchema TableSchema
+			if err := json.Unmarshal(msg.Value, &tableSchema); err != nil {
+				glog.Warningf("decode schema %s failed: %v", msg.Key, err)
+				continue
+			}
+			tableSchemas = append(tableSchemas, &tableSchema)
+		}
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, tableSchema := range tableSchemas {
+		s.add(tableSchema)
+	}
+	return nil
+}
+
+// add the version to the versions of its table, ordered by version
+func (s *SchemaHistory) add(tableSchema *TableSchema) {
+	if tableSchema.Version <= 0 {
+		return
+	}
+	if s.versions == nil {
+		s.versions = make(map[Table][]*TableSchema)
+	}
+	table := Table{Database: tableSchema.Database, Name: tableSchema.Table}
+	versions := s.versions[table]
+	for len(versions) < tableSchema.Version {
+		versions = append(versions, nil)
+	}
+	versions[tableSchema.Version-1] = tableSchema
+	s.versions[table] = versions
+}
+
+func (s *SchemaHistory) publish(tableSchema *TableSchema) error {
+	if s.Producer == nil || s.Topic == "" {
+		return nil
+	}
+	value, err := json.Marshal(tableSchema)
+	if err != nil {
+		return errors.Wrap(err, "encode schema failed")
+	}
+	_, _, err = s.Producer.SendMessage(&sarama.ProducerMessage{
+		Topic: s.Topic,
+		Key:   sarama.StringEncoder(tableSchema.Key()),
+		Value: sarama.ByteEncoder(value),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "publish schema %s failed", tableSchema.Key())
+	}
+	return nil
+}
+
+// SchemaTracker sets the schema version of each record, new schemas are added to the history
+type SchemaTracker struct {
+	History *SchemaHistory
+
+	schema  *avro.Schema
+	version int
+}
+
+// Process sets the version of the record schema
+func (s *SchemaTracker) Process(record *Record) ([]*Record, error) {
+	if record.Schema == nil {
+		return []*Record{record}, nil
+	}
+	if record.Schema != s.schema {
+		tableSchema, err := s.History.Version(record.Table, record.Schema)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get schema version of %s failed", record.Table)
+		}
+		s.schema, s.version = record.Schema, tableSchema.Version
+	}
+	record.Version = s.version
+	return []*Record{record}, nil
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const addressSchema = `{"namespace": "MaxScaleChangeDataSchema.avro", "type": "record", "name": "ChangeRecord", "fields": [
+	{"name": "domain", "type": "int"},
+	{"name": "event_type", "type": {"type": "enum", "name": "EVENT_TYPES", "symbols": ["insert", "update_before", "update_after", "delete"]}},
+	{"name": "id", "type": "int", "real_type": "int", "length": -1},
+	{"name": "street", "type": ["null", "string"], "real_type": "varchar", "length": 100}
+]}`
+
+func parseSchema(schema string) *avro.Schema {
+	result, err := avro.ParseSchema([]byte(schema))
+	Expect(err).To(BeNil())
+	return result
+}
+
+var _ = Describe("TableSchema", func() {
+	It("contains the columns without metadata fields", func() {
+		tableSchema, err := cdc.NewTableSchema(cdc.Table{Database: "shop", Name: "address"}, 3, parseSchema(addressSchema))
+		Expect(err).To(BeNil())
+		Expect(tableSchema.Key()).To(Equal("shop.address.3"))
+		Expect(tableSchema.Columns).To(Equal([]cdc.Column{
+			{Name: "id", Type: "int", RealType: "int"},
+			{Name: "street", Type: "string", RealType: "varchar", Length: 100, Nullable: true},
+		}))
+	})
+
+	It("returns error for schema that is not a record", func() {
+		_, err := cdc.NewTableSchema(cdc.Table{Database: "shop", Name: "address"}, 1, parseSchema(`"string"`))
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("SchemaHistory", func() {
+	var producer *mocks.SyncProducer
+	var history *cdc.SchemaHistory
+	table := cdc.Table{Database: "test", Name: "names"}
+
+	BeforeEach(func() {
+		producer = &mocks.SyncProducer{}
+		history = &cdc.SchemaHistory{
+			Producer: producer,
+			Topic:    "schemas",
+		}
+	})
+
+	It("publishes new versions keyed by table and version", func() {
+		version, err := history.Version(table, parseSchema(namesSchema))
+		Expect(err).To(BeNil())
+		Expect(version.Version).To(Equal(1))
+		Expect(producer.SendMessageCallCount()).To(Equal(1))
+		msg := producer.SendMessageArgsForCall(0)
+		Expect(msg.Topic).To(Equal("schemas"))
+		Expect(msg.Key).To(Equal(sarama.StringEncoder("test.names.1")))
+		value, err := msg.Value.Encode()
+		Expect(err).To(BeNil())
+		var published cdc.TableSchema
+		Expect(json.Unmarshal(value, &published)).To(BeNil())
+		Expect(published.Columns).To(HaveLen(2))
+	})
+
+	It("returns the known version of an equal schema", func() {
+		_, err := history.Version(table, parseSchema(namesSchema))
+		Expect(err).To(BeNil())
+		version, err := history.Version(table, parseSchema(namesSchema))
+		Expect(err).To(BeNil())
+		Expect(version.Version).To(Equal(1))
+		Expect(producer.SendMessageCallCount()).To(Equal(1))
+	})
+
+	It("adds changed schemas as next version", func() {
+		_, err := history.Version(table, parseSchema(namesSchema))
+		Expect(err).To(BeNil())
+		version, err := history.Version(table, parseSchema(addressSchema))
+		Expect(err).To(BeNil())
+		Expect(version.Version).To(Equal(2))
+		Expect(history.Latest(table)).To(Equal(version))
+		Expect(history.Latest(cdc.Table{Database: "test", Name: "other"})).To(BeNil())
+	})
+
+	It("does not block other tables while publishing", func() {
+		published := make(chan struct{})
+		release := make(chan struct{})
+		producer.SendMessageStub = func(msg *sarama.ProducerMessage) (int32, int64, error) {
+			if msg.Key == sarama.StringEncoder("test.names.1") {
+				close(published)
+				<-release
+			}
+			return 0, 0, nil
+		}
+		done := make(chan error, 1)
+		go func() {
+			_, err := history.Version(table, parseSchema(namesSchema))
+			done <- err
+		}()
+		<-published
+		version, err := history.Version(cdc.Table{Database: "test", Name: "address"}, parseSchema(addressSchema))
+		Expect(err).To(BeNil())
+		Expect(version.Version).To(Equal(1))
+		Expect(history.Latest(cdc.Table{Database: "test", Name: "address"})).To(Equal(version))
+		close(release)
+		Expect(<-done).To(BeNil())
+		Expect(history.Latest(table).Version).To(Equal(1))
+	})
+
+	It("keeps versions in memory without topic", func() {
+		history = &cdc.SchemaHistory{}
+		version, err := history.Version(table, parseSchema(namesSchema))
+		Expect(err).To(BeNil())
+		Expect(version.Version).To(Equal(1))
+	})
+
+	It("continues the versions loaded from the topic", func() {
+		first, err := cdc.NewTableSchema(table, 1, parseSchema(namesSchema))
+		Expect(err).To(BeNil())
+		value, err := json.Marshal(first)
+		Expect(err).To(BeNil())
+		fetchResponse := &sarama.FetchResponse{}
+		fetchResponse.AddMessage("schemas", 0, sarama.StringEncoder(first.Key()), sarama.ByteEncoder(value), 0)
+		broker := sarama.NewMockBroker(GinkgoT(), 1)
+		defer broker.Close()
+		broker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest": sarama.NewMockMetadataResponse(GinkgoT()).
+				SetBroker(broker.Addr(), broker.BrokerID()).
+				SetLeader("schemas", 0, broker.BrokerID()),
+			"OffsetRequest": sarama.NewMockOffsetResponse(GinkgoT()).
+				SetOffset("schemas", 0, sarama.OffsetOldest, 0).
+				SetOffset("schemas", 0, sarama.OffsetNewest, 1),
+			"FetchRequest": sarama.NewMockWrapper(fetchResponse),
+		})
+		client, err := sarama.NewClient([]string{broker.Addr()}, sarama.NewConfig())
+		Expect(err).To(BeNil())
+		defer client.Close()
+
+		Expect(history.Load(client, 5*time.Second)).To(BeNil())
+		version, err := history.Version(table, parseSchema(namesSchema))
+		Expect(err).To(BeNil())
+		Expect(version.Version).To(Equal(1))
+		version, err = history.Version(table, parseSchema(addressSchema))
+		Expect(err).To(BeNil())
+		Expect(version.Version).To(Equal(2))
+		Expect(producer.SendMessageCallCount()).To(Equal(1))
+	})
+})
+
+var _ = Describe("SchemaTracker", func() {
+	It("sets the schema version of records", func() {
+		tracker := &cdc.SchemaTracker{History: &cdc.SchemaHistory{}}
+		record := namesRecord("JSON", map[string]interface{}{"domain": 0, "id": 1})
+		records, err := tracker.Process(record)
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Version).To(Equal(1))
+	})
+
+	It("keeps records without schema", func() {
+		tracker := &cdc.SchemaTracker{History: &cdc.SchemaHistory{}}
+		records, err := tracker.Process(jsonRecord(`{"domain": 0}`))
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Version).To(Equal(0))
+	})
+})
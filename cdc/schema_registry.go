@@ -0,0 +1,187 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// Subject name strategies of the Confluent Schema Registry
+const (
+	TopicNameStrategy       = "TopicNameStrategy"
+	RecordNameStrategy      = "RecordNameStrategy"
+	TopicRecordNameStrategy = "TopicRecordNameStrategy"
+	// TableNameStrategy uses DATABASE.TABLE, because Maxscale uses the same record name for all tables
+	TableNameStrategy = "TableNameStrategy"
+)
+
+// SchemaRegistry interface for register Avro schemas
+//go:generate counterfeiter -o ../mocks/schema_registry.go --fake-name SchemaRegistry . SchemaRegistry
+type SchemaRegistry interface {
+	// Register the schema under the given subject and return its id
+	Register(subject string, schema *avro.Schema) (int, error)
+}
+
+// defaultSchemaRegistryHTTPClient is used without HTTPClient, a hanging registry must not block the streamers forever
+var defaultSchemaRegistryHTTPClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
+// SchemaRegistryClient registers schemas in the Confluent Schema Registry
+// https://docs.confluent.io/current/schema-registry/docs/api.html
+type SchemaRegistryClient struct {
+	URL string
+	// HTTPClient is optional, the default client times out after 30 seconds
+	HTTPClient *http.Client
+
+	mux   sync.Mutex
+	cache map[string]int
+}
+
+// Register the schema under the given subject. Known schemas are returned from cache.
+// A changed schema is registered as new version of the subject.
+// The cache is not locked during the request, registering the same schema twice returns the same id.
+func (s *SchemaRegistryClient) Register(subject string, schema *avro.Schema) (int, error) {
+	cacheKey := subject + ":" + schema.String()
+	s.mux.Lock()
+	id, ok := s.cache[cacheKey]
+	s.mux.Unlock()
+	if ok {
+		return id, nil
+	}
+	id, err := s.register(subject, schema)
+	if err != nil {
+		return 0, err
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.cache == nil {
+		s.cache = make(map[string]int)
+	}
+	s.cache[cacheKey] = id
+	return id, nil
+}
+
+// register posts the schema to the registry and returns its id
+func (s *SchemaRegistryClient) register(subject string, schema *avro.Schema) (int, error) {
+	body := &bytes.Buffer{}
+	err := json.NewEncoder(body).Encode(map[string]string{
+		"schema": schema.String(),
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "encode request failed")
+	}
+	req, err := http.NewRequest(
+		http.MethodPost,
+		fmt.Sprintf("%s/subjects/%s/versions", strings.TrimSuffix(s.URL, "/"), url.PathEscape(subject)),
+		body,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "create request failed")
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	resp, err := s.httpClient().Do(req)
+	if err != nil {
+		return 0, errors.Wrapf(err, "register schema for subject %s failed", subject)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return 0, errors.Errorf("register schema for subject %s failed with status %d", subject, resp.StatusCode)
+	}
+	var data struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return 0, errors.Wrap(err, "decode response failed")
+	}
+	glog.V(1).Infof("registered schema for subject %s with id %d", subject, data.ID)
+	return data.ID, nil
+}
+
+func (s *SchemaRegistryClient) httpClient() *http.Client {
+	if s.HTTPClient == nil {
+		return defaultSchemaRegistryHTTPClient
+	}
+	return s.HTTPClient
+}
+
+// ConfluentEncoder encodes Avro records in the Confluent wire format.
+// Magic byte 0, the schema id as 4 byte big endian followed by the Avro binary data.
+// https://docs.confluent.io/current/schema-registry/docs/serializer-formatter.html#wire-format
+type ConfluentEncoder struct {
+	SchemaRegistry      SchemaRegistry
+	SubjectNameStrategy string
+}
+
+// Encode the record for the given topic
+func (c *ConfluentEncoder) Encode(topic string, record *Record) ([]byte, error) {
+	if record.Format != "AVRO" {
+		return nil, errors.Errorf("format %s not supported", record.Format)
+	}
+	if record.Schema == nil {
+		return nil, errors.New("schema missing")
+	}
+	subject, err := c.subject(topic, record.Table, record.Schema, "value")
+	if err != nil {
+		return nil, err
+	}
+	return c.encode(subject, record.Schema, record.Data)
+}
+
+// EncodeKey encodes the Avro binary key of the record written with the given key schema
+func (c *ConfluentEncoder) EncodeKey(topic string, record *Record, schema *avro.Schema, data []byte) ([]byte, error) {
+	subject, err := c.subject(topic, record.Table, schema, "key")
+	if err != nil {
+		return nil, err
+	}
+	return c.encode(subject, schema, data)
+}
+
+func (c *ConfluentEncoder) encode(subject string, schema *avro.Schema, data []byte) ([]byte, error) {
+	id, err := c.SchemaRegistry.Register(subject, schema)
+	if err != nil {
+		return nil, errors.Wrap(err, "register schema failed")
+	}
+	buf := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(buf[1:], uint32(id))
+	return append(buf, data...), nil
+}
+
+func (c *ConfluentEncoder) subject(topic string, table Table, schema *avro.Schema, suffix string) (string, error) {
+	switch c.SubjectNameStrategy {
+	case TopicNameStrategy, "":
+		return topic + "-" + suffix, nil
+	case RecordNameStrategy:
+		return schema.FullName(), nil
+	case TopicRecordNameStrategy:
+		return topic + "-" + schema.FullName(), nil
+	case TableNameStrategy:
+		return table.String() + "-" + suffix, nil
+	default:
+		return "", errors.Errorf("unknown subject name strategy %s", c.SubjectNameStrategy)
+	}
+}
+
+// ValidSubjectNameStrategy returns true if the given strategy is known
+func ValidSubjectNameStrategy(strategy string) bool {
+	switch strategy {
+	case TopicNameStrategy, RecordNameStrategy, TopicRecordNameStrategy, TableNameStrategy:
+		return true
+	default:
+		return false
+	}
+}
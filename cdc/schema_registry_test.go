@@ -0,0 +1,203 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+// fakeSchemaRegistry is a minimal stand-in for the register endpoint of the Confluent Schema Registry
+type fakeSchemaRegistry struct {
+	mux      sync.Mutex
+	requests int
+	subjects map[string][]string
+	schemas  []string
+}
+
+func (f *fakeSchemaRegistry) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.requests++
+	if !strings.HasPrefix(req.URL.Path, "/subjects/") || !strings.HasSuffix(req.URL.Path, "/versions") || req.Method != http.MethodPost {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+	subject := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/subjects/"), "/versions")
+	var data struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&data); err != nil {
+		resp.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+	id := -1
+	for i, schema := range f.schemas {
+		if schema == data.Schema {
+			id = i + 1
+		}
+	}
+	if id == -1 {
+		f.schemas = append(f.schemas, data.Schema)
+		id = len(f.schemas)
+		f.subjects[subject] = append(f.subjects[subject], data.Schema)
+	}
+	_ = json.NewEncoder(resp).Encode(map[string]int{"id": id})
+}
+
+var _ = Describe("SchemaRegistryClient", func() {
+	var registry *fakeSchemaRegistry
+	var server *httptest.Server
+	var client *cdc.SchemaRegistryClient
+	var schema *avro.Schema
+
+	BeforeEach(func() {
+		registry = &fakeSchemaRegistry{subjects: make(map[string][]string)}
+		server = httptest.NewServer(registry)
+		client = &cdc.SchemaRegistryClient{URL: server.URL}
+		var err error
+		schema, err = avro.ParseSchema([]byte(`{"type":"record","name":"r","fields":[{"name":"a","type":"int"}]}`))
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("registers schema and returns id", func() {
+		id, err := client.Register("mytopic-value", schema)
+		Expect(err).To(BeNil())
+		Expect(id).To(Equal(1))
+		Expect(registry.subjects["mytopic-value"]).To(Equal([]string{schema.String()}))
+	})
+
+	It("returns id of known schema from cache", func() {
+		_, err := client.Register("mytopic-value", schema)
+		Expect(err).To(BeNil())
+		id, err := client.Register("mytopic-value", schema)
+		Expect(err).To(BeNil())
+		Expect(id).To(Equal(1))
+		Expect(registry.requests).To(Equal(1))
+	})
+
+	It("registers changed schema as new version", func() {
+		_, err := client.Register("mytopic-value", schema)
+		Expect(err).To(BeNil())
+		changed, err := avro.ParseSchema([]byte(`{"type":"record","name":"r","fields":[{"name":"a","type":"int"},{"name":"b","type":["null","string"]}]}`))
+		Expect(err).To(BeNil())
+		id, err := client.Register("mytopic-value", changed)
+		Expect(err).To(BeNil())
+		Expect(id).To(Equal(2))
+		Expect(registry.subjects["mytopic-value"]).To(HaveLen(2))
+	})
+
+	It("returns error if registry fails", func() {
+		client.URL = server.URL + "/banana"
+		_, err := client.Register("mytopic-value", schema)
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("escapes the subject", func() {
+		_, err := client.Register("my?topic#value", schema)
+		Expect(err).To(BeNil())
+		Expect(registry.subjects["my?topic#value"]).To(Equal([]string{schema.String()}))
+	})
+
+	It("does not block other subjects during a request", func() {
+		started, release := make(chan struct{}), make(chan struct{})
+		slow := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			if strings.Contains(req.URL.Path, "/slow-value/") {
+				close(started)
+				<-release
+			}
+			registry.ServeHTTP(resp, req)
+		}))
+		defer slow.Close()
+		client = &cdc.SchemaRegistryClient{URL: slow.URL, HTTPClient: &http.Client{Timeout: 5 * time.Second}}
+		done := make(chan error)
+		go func() {
+			_, err := client.Register("slow-value", schema)
+			done <- err
+		}()
+		<-started
+		_, err := client.Register("fast-value", schema)
+		Expect(err).To(BeNil())
+		close(release)
+		Expect(<-done).To(BeNil())
+	})
+})
+
+var _ = Describe("ConfluentEncoder", func() {
+	var registry *mocks.SchemaRegistry
+	var encoder *cdc.ConfluentEncoder
+	var record *cdc.Record
+
+	BeforeEach(func() {
+		registry = &mocks.SchemaRegistry{}
+		registry.RegisterReturns(258, nil)
+		encoder = &cdc.ConfluentEncoder{
+			SchemaRegistry:      registry,
+			SubjectNameStrategy: cdc.TopicNameStrategy,
+		}
+		schema, err := avro.ParseSchema([]byte(`{"type":"record","name":"ChangeRecord","namespace":"MaxScaleChangeDataSchema.avro","fields":[{"name":"a","type":"int"}]}`))
+		Expect(err).To(BeNil())
+		record = &cdc.Record{
+			Table:  cdc.Table{Database: "mydb", Name: "mytable"},
+			Format: "AVRO",
+			Schema: schema,
+			Data:   []byte{2},
+		}
+	})
+
+	It("encodes in wire format", func() {
+		value, err := encoder.Encode("mytopic", record)
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal([]byte{0, 0, 0, 1, 2, 2}))
+		Expect(registry.RegisterCallCount()).To(Equal(1))
+		subject, schema := registry.RegisterArgsForCall(0)
+		Expect(subject).To(Equal("mytopic-value"))
+		Expect(schema).To(Equal(record.Schema))
+	})
+
+	for strategy, expected := range map[string]string{
+		cdc.TopicNameStrategy:       "mytopic-value",
+		cdc.RecordNameStrategy:      "MaxScaleChangeDataSchema.avro.ChangeRecord",
+		cdc.TopicRecordNameStrategy: "mytopic-MaxScaleChangeDataSchema.avro.ChangeRecord",
+		cdc.TableNameStrategy:       "mydb.mytable-value",
+	} {
+		strategy, expected := strategy, expected
+		It(fmt.Sprintf("uses subject %s for %s", expected, strategy), func() {
+			encoder.SubjectNameStrategy = strategy
+			_, err := encoder.Encode("mytopic", record)
+			Expect(err).To(BeNil())
+			subject, _ := registry.RegisterArgsForCall(0)
+			Expect(subject).To(Equal(expected))
+		})
+	}
+
+	It("returns error for JSON records", func() {
+		record.Format = "JSON"
+		_, err := encoder.Encode("mytopic", record)
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error if register fails", func() {
+		registry.RegisterReturns(0, errors.New("banana"))
+		_, err := encoder.Encode("mytopic", record)
+		Expect(err).NotTo(BeNil())
+	})
+})
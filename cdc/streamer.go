@@ -10,33 +10,44 @@ import (
 	"sync"
 
 	"github.com/bborbe/run"
+	"github.com/pkg/errors"
 )
 
 // Reader interface for the Streamer
 //go:generate counterfeiter -o ../mocks/reader.go --fake-name Reader . Reader
 type Reader interface {
 	// Read changes and send them to the given channel
-	Read(ctx context.Context, gtid *GTID, ch chan<- []byte) error
+	Read(ctx context.Context, gtid *GTID, ch chan<- *Record) error
 }
 
 // Sender interface for the Streamer
 //go:generate counterfeiter -o ../mocks/sender.go --fake-name Sender . Sender
 type Sender interface {
-	Send(ctx context.Context, ch <-chan []byte) error
+	Send(ctx context.Context, ch <-chan *Record) error
 }
 
-// Streamer coordinates read and send of CDC records
+// Processor interface for the Streamer
+//go:generate counterfeiter -o ../mocks/processor.go --fake-name Processor . Processor
+type Processor interface {
+	// Process the record and return the records to send. Return no record to drop it
+	// or a dropped record to drop it and advance the checkpoint past it.
+	Process(record *Record) ([]*Record, error)
+}
+
+// Streamer coordinates read, process and send of CDC records
 type Streamer struct {
-	GTID   *GTID
-	Reader Reader
-	Sender Sender
+	GTID       *GTID
+	Reader     Reader
+	Processors []Processor
+	Sender     Sender
 }
 
-// Run read and send of CDC records
+// Run read, process and send of CDC records
 func (s *Streamer) Run(ctx context.Context) error {
-	ch := make(chan []byte, runtime.NumCPU())
+	ch := make(chan *Record, runtime.NumCPU())
+	processed := make(chan *Record, runtime.NumCPU())
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(3)
 	go func() {
 		wg.Wait()
 		close(ch)
@@ -49,7 +60,49 @@ func (s *Streamer) Run(ctx context.Context) error {
 		},
 		func(ctx context.Context) error {
 			defer wg.Done()
-			return s.Sender.Send(ctx, ch)
+			defer close(processed)
+			return s.process(ctx, ch, processed)
+		},
+		func(ctx context.Context) error {
+			defer wg.Done()
+			return s.Sender.Send(ctx, processed)
 		},
 	)
 }
+
+// process all records with the processors in the given order
+func (s *Streamer) process(ctx context.Context, in <-chan *Record, out chan<- *Record) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case record, ok := <-in:
+			if !ok {
+				return nil
+			}
+			records := []*Record{record}
+			for _, processor := range s.Processors {
+				var next []*Record
+				for _, r := range records {
+					if r.Dropped {
+						next = append(next, r)
+						continue
+					}
+					result, err := processor.Process(r)
+					if err != nil {
+						return errors.Wrapf(err, "process record of %s failed", r.Table)
+					}
+					next = append(next, result...)
+				}
+				records = next
+			}
+			for _, r := range records {
+				select {
+				case <-ctx.Done():
+					return nil
+				case out <- r:
+				}
+			}
+		}
+	}
+}
@@ -21,13 +21,13 @@ var _ = Describe("MaxscaleReader", func() {
 
 	BeforeEach(func() {
 		reader = &mocks.Reader{}
-		reader.ReadStub = func(i context.Context, gtid *cdc.GTID, bytes chan<- []byte) error {
-			bytes <- []byte("hello world")
+		reader.ReadStub = func(i context.Context, gtid *cdc.GTID, records chan<- *cdc.Record) error {
+			records <- &cdc.Record{Data: []byte("hello world")}
 			return nil
 		}
 		sender = &mocks.Sender{}
-		sender.SendStub = func(i context.Context, bytes <-chan []byte) error {
-			for range bytes {
+		sender.SendStub = func(i context.Context, records <-chan *cdc.Record) error {
+			for range records {
 			}
 			return nil
 		}
@@ -64,4 +64,83 @@ var _ = Describe("MaxscaleReader", func() {
 		Expect(ctx).NotTo(BeNil())
 		Expect(ch).NotTo(BeNil())
 	})
+
+	It("sends processed records", func() {
+		processor := &mocks.Processor{}
+		processor.ProcessStub = func(record *cdc.Record) ([]*cdc.Record, error) {
+			return []*cdc.Record{record, {Data: []byte("processed")}}, nil
+		}
+		var sent []string
+		done := make(chan struct{})
+		sender.SendStub = func(i context.Context, records <-chan *cdc.Record) error {
+			defer close(done)
+			for record := range records {
+				sent = append(sent, string(record.Data))
+			}
+			return nil
+		}
+		reader.ReadStub = func(ctx context.Context, gtid *cdc.GTID, records chan<- *cdc.Record) error {
+			records <- &cdc.Record{Data: []byte("hello world")}
+			<-ctx.Done()
+			return nil
+		}
+		streamer.Processors = []cdc.Processor{processor}
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			defer cancel()
+			Eventually(processor.ProcessCallCount).Should(Equal(1))
+			time.Sleep(10 * time.Millisecond)
+		}()
+		Expect(streamer.Run(ctx)).To(BeNil())
+		<-done
+		Expect(sent).To(Equal([]string{"hello world", "processed"}))
+	})
+
+	It("drops records if processor returns none", func() {
+		processor := &mocks.Processor{}
+		processor.ProcessReturns(nil, nil)
+		sender.SendStub = func(i context.Context, records <-chan *cdc.Record) error {
+			for range records {
+				Fail("unexpected record")
+			}
+			return nil
+		}
+		streamer.Processors = []cdc.Processor{processor}
+		err := streamer.Run(context.Background())
+		Expect(err).To(BeNil())
+	})
+
+	It("sends dropped records without passing them to further processors", func() {
+		filter := &mocks.Processor{}
+		filter.ProcessStub = func(record *cdc.Record) ([]*cdc.Record, error) {
+			return []*cdc.Record{{Data: record.Data, Dropped: true}}, nil
+		}
+		processor := &mocks.Processor{}
+		var sent []*cdc.Record
+		done := make(chan struct{})
+		sender.SendStub = func(i context.Context, records <-chan *cdc.Record) error {
+			defer close(done)
+			for record := range records {
+				sent = append(sent, record)
+			}
+			return nil
+		}
+		reader.ReadStub = func(ctx context.Context, gtid *cdc.GTID, records chan<- *cdc.Record) error {
+			records <- &cdc.Record{Data: []byte("hello world")}
+			<-ctx.Done()
+			return nil
+		}
+		streamer.Processors = []cdc.Processor{filter, processor}
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			defer cancel()
+			Eventually(filter.ProcessCallCount).Should(Equal(1))
+			time.Sleep(10 * time.Millisecond)
+		}()
+		Expect(streamer.Run(ctx)).To(BeNil())
+		<-done
+		Expect(processor.ProcessCallCount()).To(Equal(0))
+		Expect(sent).To(HaveLen(1))
+		Expect(sent[0].Dropped).To(BeTrue())
+	})
 })
@@ -0,0 +1,98 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Table identifies a table by database and name
+type Table struct {
+	Database string
+	Name     string
+}
+
+// String representation of the table in format DATABASE.TABLE
+func (t Table) String() string {
+	return fmt.Sprintf("%s.%s", t.Database, t.Name)
+}
+
+// ParseTable return Table for the given string in format DATABASE.TABLE
+func ParseTable(table string) (*Table, error) {
+	parts := strings.Split(strings.TrimSpace(table), ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errors.Errorf("parse table %s failed", table)
+	}
+	return &Table{
+		Database: parts[0],
+		Name:     parts[1],
+	}, nil
+}
+
+// ParseTables return all tables of the given comma separated list. Duplicates are removed.
+func ParseTables(tables string) ([]Table, error) {
+	var result []Table
+	seen := make(map[Table]bool)
+	for _, part := range strings.Split(tables, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		table, err := ParseTable(part)
+		if err != nil {
+			return nil, err
+		}
+		if seen[*table] {
+			continue
+		}
+		seen[*table] = true
+		result = append(result, *table)
+	}
+	return result, nil
+}
+
+// TableLists are lists of values by table pattern, e.g. shop.*=update_before+delete
+type TableLists map[string][]string
+
+// ParseTableLists parses a comma separated list of PATTERN=VALUE+VALUE.
+// Patterns support the wildcards of path.Match like the patterns of TableMatcher.
+func ParseTableLists(lists string) (TableLists, error) {
+	result := make(TableLists)
+	for _, entry := range strings.Split(lists, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("parse table list %s failed", entry)
+		}
+		pattern := strings.TrimSpace(parts[0])
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return nil, errors.Errorf("invalid pattern in table list %s", entry)
+		}
+		for _, value := range strings.Split(parts[1], "+") {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				return nil, errors.Errorf("empty value in table list %s", entry)
+			}
+			result[pattern] = append(result[pattern], value)
+		}
+	}
+	return result, nil
+}
+
+// Values returns the values of all patterns that match the table
+func (t TableLists) Values(table Table) []string {
+	var result []string
+	for pattern, values := range t {
+		if ok, _ := path.Match(pattern, table.String()); ok {
+			result = append(result, values...)
+		}
+	}
+	return result
+}
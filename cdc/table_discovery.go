@@ -0,0 +1,153 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bborbe/run"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// TableLister interface for discover tables
+//go:generate counterfeiter -o ../mocks/table_lister.go --fake-name TableLister . TableLister
+type TableLister interface {
+	// List all tables currently available for streaming
+	List(ctx context.Context) ([]Table, error)
+}
+
+// AvroDirTableLister lists all tables the Maxscale avrorouter has written a schema file for.
+// The avrorouter writes DATABASE.TABLE.VERSION.avsc into its avrodir for every table it knows about.
+type AvroDirTableLister struct {
+	Dir string
+}
+
+// List all tables found in the avro directory
+func (a *AvroDirTableLister) List(ctx context.Context) ([]Table, error) {
+	files, err := ioutil.ReadDir(a.Dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read dir %s failed", a.Dir)
+	}
+	var result []Table
+	seen := make(map[Table]bool)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".avsc") {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(file.Name(), ".avsc"), ".")
+		if len(parts) < 3 {
+			glog.V(3).Infof("skip file %s", file.Name())
+			continue
+		}
+		table := Table{
+			Database: parts[0],
+			Name:     strings.Join(parts[1:len(parts)-1], "."),
+		}
+		if seen[table] {
+			continue
+		}
+		seen[table] = true
+		result = append(result, table)
+	}
+	return result, nil
+}
+
+// IsTablePattern returns true if the given DATABASE.TABLE contains wildcards
+func IsTablePattern(table string) bool {
+	return strings.ContainsAny(table, "*?[")
+}
+
+// TableMatcher selects tables by include and exclude patterns.
+// Patterns have the format DATABASE.TABLE and support the wildcards of path.Match, e.g. shop.* or shop.order_*
+type TableMatcher struct {
+	Include []string
+	Exclude []string
+}
+
+// Match returns true if the table matches any include and no exclude pattern
+func (t *TableMatcher) Match(table Table) bool {
+	return matchAny(t.Include, table) && !matchAny(t.Exclude, table)
+}
+
+// Validate returns an error if a pattern is malformed
+func (t *TableMatcher) Validate() error {
+	for _, pattern := range append(t.Include, t.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Wrapf(err, "invalid pattern %s", pattern)
+		}
+	}
+	return nil
+}
+
+func matchAny(patterns []string, table Table) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, table.String()); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// TableDiscovery starts a runner for each table. Static tables are started immediately,
+// tables found by the lister are started as soon as they match the matcher.
+type TableDiscovery struct {
+	Tables   []Table
+	Lister   TableLister
+	Matcher  *TableMatcher
+	Interval time.Duration
+	Runner   func(table Table) run.RunFunc
+}
+
+// Run discovery and blocks until all runners finished or the context is canceled
+func (t *TableDiscovery) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	running := make(map[Table]bool)
+	start := func(table Table) {
+		if running[table] {
+			return
+		}
+		running[table] = true
+		glog.V(1).Infof("start streaming of %s", table)
+		runner := t.Runner(table)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := runner(ctx); err != nil {
+				glog.Warningf("stream %s failed: %v", table, err)
+			}
+		}()
+	}
+
+	for _, table := range t.Tables {
+		start(table)
+	}
+	if t.Lister == nil {
+		return nil
+	}
+	for {
+		tables, err := t.Lister.List(ctx)
+		if err != nil {
+			glog.Warningf("list tables failed: %v", err)
+		}
+		for _, table := range tables {
+			if t.Matcher.Match(table) {
+				start(table)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(t.Interval):
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/mocks"
+	"github.com/bborbe/run"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TableMatcher", func() {
+	var matcher *cdc.TableMatcher
+
+	BeforeEach(func() {
+		matcher = &cdc.TableMatcher{
+			Include: []string{"shop.order_*", "crm.*"},
+			Exclude: []string{"shop.order_tmp"},
+		}
+	})
+
+	It("matches include pattern", func() {
+		Expect(matcher.Match(cdc.Table{Database: "shop", Name: "order_items"})).To(BeTrue())
+	})
+
+	It("matches whole database", func() {
+		Expect(matcher.Match(cdc.Table{Database: "crm", Name: "customers"})).To(BeTrue())
+	})
+
+	It("not matches other table", func() {
+		Expect(matcher.Match(cdc.Table{Database: "shop", Name: "customers"})).To(BeFalse())
+	})
+
+	It("not matches excluded table", func() {
+		Expect(matcher.Match(cdc.Table{Database: "shop", Name: "order_tmp"})).To(BeFalse())
+	})
+
+	It("returns error for invalid pattern", func() {
+		matcher.Include = []string{"shop.[banana"}
+		Expect(matcher.Validate()).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("AvroDirTableLister", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "avro-dir")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	It("lists tables of schema files", func() {
+		for _, name := range []string{
+			"shop.orders.000001.avsc",
+			"shop.orders.000001.avro",
+			"shop.orders.000002.avsc",
+			"crm.customers.000001.avsc",
+			"avro.index",
+		} {
+			Expect(ioutil.WriteFile(path.Join(dir, name), []byte{}, 0600)).To(BeNil())
+		}
+		lister := &cdc.AvroDirTableLister{Dir: dir}
+		tables, err := lister.List(context.Background())
+		Expect(err).To(BeNil())
+		Expect(tables).To(ConsistOf(
+			cdc.Table{Database: "shop", Name: "orders"},
+			cdc.Table{Database: "crm", Name: "customers"},
+		))
+	})
+
+	It("returns error if dir not exists", func() {
+		lister := &cdc.AvroDirTableLister{Dir: path.Join(dir, "banana")}
+		_, err := lister.List(context.Background())
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("TableDiscovery", func() {
+	var lister *mocks.TableLister
+	var discovery *cdc.TableDiscovery
+	var mux sync.Mutex
+	var started []cdc.Table
+
+	BeforeEach(func() {
+		started = nil
+		lister = &mocks.TableLister{}
+		discovery = &cdc.TableDiscovery{
+			Tables:   []cdc.Table{{Database: "shop", Name: "static"}},
+			Matcher:  &cdc.TableMatcher{Include: []string{"shop.order_*"}},
+			Interval: 10 * time.Millisecond,
+			Runner: func(table cdc.Table) run.RunFunc {
+				return func(ctx context.Context) error {
+					mux.Lock()
+					started = append(started, table)
+					mux.Unlock()
+					<-ctx.Done()
+					return nil
+				}
+			},
+		}
+	})
+
+	startedTables := func() []cdc.Table {
+		mux.Lock()
+		defer mux.Unlock()
+		return append([]cdc.Table{}, started...)
+	}
+
+	It("starts static tables without lister", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		Expect(discovery.Run(ctx)).To(BeNil())
+		Expect(startedTables()).To(Equal([]cdc.Table{{Database: "shop", Name: "static"}}))
+	})
+
+	It("starts matching tables once", func() {
+		discovery.Lister = lister
+		lister.ListReturnsOnCall(0, []cdc.Table{{Database: "shop", Name: "customers"}}, nil)
+		lister.ListReturns([]cdc.Table{
+			{Database: "shop", Name: "customers"},
+			{Database: "shop", Name: "order_items"},
+		}, nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		Expect(discovery.Run(ctx)).To(BeNil())
+		Expect(lister.ListCallCount()).To(BeNumerically(">", 1))
+		Expect(startedTables()).To(ConsistOf(
+			cdc.Table{Database: "shop", Name: "static"},
+			cdc.Table{Database: "shop", Name: "order_items"},
+		))
+	})
+})
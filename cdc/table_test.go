@@ -0,0 +1,82 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Table", func() {
+
+	It("parse string", func() {
+		table, err := cdc.ParseTable("mydb.mytable")
+		Expect(err).To(BeNil())
+		Expect(table).NotTo(BeNil())
+		Expect(table.Database).To(Equal("mydb"))
+		Expect(table.Name).To(Equal("mytable"))
+	})
+
+	It("string return", func() {
+		table := cdc.Table{Database: "mydb", Name: "mytable"}
+		Expect(table.String()).To(Equal("mydb.mytable"))
+	})
+
+	It("returns error if database is missing", func() {
+		_, err := cdc.ParseTable("mytable")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error if table is empty", func() {
+		_, err := cdc.ParseTable("mydb.")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("parse list", func() {
+		tables, err := cdc.ParseTables("mydb.a, mydb.b,otherdb.c")
+		Expect(err).To(BeNil())
+		Expect(tables).To(Equal([]cdc.Table{
+			{Database: "mydb", Name: "a"},
+			{Database: "mydb", Name: "b"},
+			{Database: "otherdb", Name: "c"},
+		}))
+	})
+
+	It("parse list removes duplicates", func() {
+		tables, err := cdc.ParseTables("mydb.a,mydb.a")
+		Expect(err).To(BeNil())
+		Expect(tables).To(HaveLen(1))
+	})
+
+	It("parse empty list", func() {
+		tables, err := cdc.ParseTables("")
+		Expect(err).To(BeNil())
+		Expect(tables).To(BeEmpty())
+	})
+
+	It("parse list returns error for invalid entry", func() {
+		_, err := cdc.ParseTables("mydb.a,banana")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("parse table lists", func() {
+		lists, err := cdc.ParseTableLists("shop.*=update_before+delete, mydb.a=blob")
+		Expect(err).To(BeNil())
+		Expect(lists).To(Equal(cdc.TableLists{
+			"shop.*": {"update_before", "delete"},
+			"mydb.a": {"blob"},
+		}))
+		Expect(lists.Values(cdc.Table{Database: "shop", Name: "order"})).To(Equal([]string{"update_before", "delete"}))
+		Expect(lists.Values(cdc.Table{Database: "mydb", Name: "b"})).To(BeEmpty())
+	})
+
+	It("parse table lists returns error for invalid entry", func() {
+		for _, lists := range []string{"shop.*", "shop.*=", "[=delete", "shop.*=insert+"} {
+			_, err := cdc.ParseTableLists(lists)
+			Expect(err).NotTo(BeNil(), lists)
+		}
+	})
+})
@@ -0,0 +1,223 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// TopicRouter returns the Kafka topic of a record
+type TopicRouter interface {
+	Topic(record *Record) (string, error)
+}
+
+var (
+	placeholderRegexp  = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+	invalidTopicRegexp = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+)
+
+// unmappedError is returned for placeholders whose value is missing, null or not mapped
+type unmappedError struct {
+	placeholder string
+	value       interface{}
+}
+
+func (u *unmappedError) Error() string {
+	if u.value == nil {
+		return fmt.Sprintf("value for placeholder %s missing", u.placeholder)
+	}
+	return fmt.Sprintf("value %v for placeholder %s not mapped", u.value, u.placeholder)
+}
+
+// builtinPlaceholders are valid in every topic template, columns must be declared
+var builtinPlaceholders = map[string]bool{
+	"database":   true,
+	"table":      true,
+	"event_type": true,
+	"domain":     true,
+	"server_id":  true,
+}
+
+// TopicTemplate routes records by a template like cdc.{database}.{table}.{event_type}.
+// Supported placeholders are {database}, {table}, {event_type}, {domain}, {server_id} and declared columns
+// of the table, e.g. orders.tenant-{tenant_id}.
+// Overrides replace the template for single tables and can contain placeholders too.
+type TopicTemplate struct {
+	Template  string
+	Overrides map[Table]string
+	// Mappings replace the values of placeholders, e.g. tenant_id 42 by acme. Values without mapping are unmapped.
+	Mappings map[string]map[string]string
+	// Default is the topic of records with missing, null or unmapped placeholder values, e.g. a dead letter topic.
+	// It can contain {database} and {table}. Without default these records fail.
+	Default string
+}
+
+// Topic returns the topic for the given record
+func (t *TopicTemplate) Topic(record *Record) (string, error) {
+	template := t.Template
+	if override, ok := t.Overrides[record.Table]; ok {
+		template = override
+	}
+	topic, err := t.render(template, record)
+	if _, ok := errors.Cause(err).(*unmappedError); ok && t.Default != "" {
+		glog.V(3).Infof("route record of %s to default topic: %v", record.Table, err)
+		return t.render(t.Default, record)
+	}
+	return topic, err
+}
+
+// render replaces the placeholders of the template with the values of the record
+func (t *TopicTemplate) render(template string, record *Record) (string, error) {
+	var values map[string]interface{}
+	var err error
+	topic := placeholderRegexp.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := strings.Trim(placeholder, "{}")
+		switch name {
+		case "database":
+			return record.Table.Database
+		case "table":
+			return record.Table.Name
+		}
+		if values == nil && err == nil {
+			values, err = record.Values()
+		}
+		if err != nil {
+			return ""
+		}
+		value := values[name]
+		if value == nil {
+			err = &unmappedError{placeholder: placeholder}
+			return ""
+		}
+		if mapping, ok := t.Mappings[name]; ok {
+			mapped, ok := mapping[fmt.Sprint(value)]
+			if !ok {
+				err = &unmappedError{placeholder: placeholder, value: value}
+				return ""
+			}
+			return mapped
+		}
+		return fmt.Sprint(value)
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "build topic of template %s failed", template)
+	}
+	return invalidTopicRegexp.ReplaceAllString(topic, "_"), nil
+}
+
+// ValidateTopicTemplate returns an error if the template is empty, contains malformed placeholders
+// or placeholders that are neither built in nor one of the given columns
+func ValidateTopicTemplate(template string, columns ...string) error {
+	if template == "" {
+		return errors.New("template empty")
+	}
+	if strings.ContainsAny(placeholderRegexp.ReplaceAllString(template, ""), "{}") {
+		return errors.Errorf("malformed placeholder in %s", template)
+	}
+	for _, match := range placeholderRegexp.FindAllStringSubmatch(template, -1) {
+		if !validPlaceholder(match[1], columns) {
+			return errors.Errorf("unknown placeholder %s", match[0])
+		}
+	}
+	return nil
+}
+
+func validPlaceholder(name string, columns []string) bool {
+	if builtinPlaceholders[name] {
+		return true
+	}
+	for _, column := range columns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTopicColumns parses a comma separated list of columns that topics can use as placeholders
+func ParseTopicColumns(columns string) ([]string, error) {
+	var result []string
+	for _, column := range strings.Split(columns, ",") {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			continue
+		}
+		if !placeholderRegexp.MatchString("{" + column + "}") {
+			return nil, errors.Errorf("invalid topic column %s", column)
+		}
+		result = append(result, column)
+	}
+	return result, nil
+}
+
+// ParseTopicMappings parses a comma separated list of PLACEHOLDER=VALUE:NAME+VALUE:NAME,
+// e.g. tenant_id=42:acme+7:globex. The placeholder must be built in or one of the given columns.
+func ParseTopicMappings(mappings string, columns ...string) (map[string]map[string]string, error) {
+	result := make(map[string]map[string]string)
+	for _, entry := range strings.Split(mappings, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || !placeholderRegexp.MatchString("{"+name+"}") {
+			return nil, errors.Errorf("parse topic mapping %s failed", entry)
+		}
+		if !validPlaceholder(name, columns) {
+			return nil, errors.Errorf("unknown placeholder %s in topic mapping %s", name, entry)
+		}
+		if result[name] == nil {
+			result[name] = make(map[string]string)
+		}
+		for _, pair := range strings.Split(parts[1], "+") {
+			values := strings.SplitN(pair, ":", 2)
+			if len(values) != 2 || strings.TrimSpace(values[0]) == "" || strings.TrimSpace(values[1]) == "" {
+				return nil, errors.Errorf("parse topic mapping %s failed", entry)
+			}
+			result[name][strings.TrimSpace(values[0])] = strings.TrimSpace(values[1])
+		}
+	}
+	return result, nil
+}
+
+// ParseTopicOverrides parses a comma separated list of DATABASE.TABLE=TOPIC.
+// Topics can contain the built in placeholders and the given columns.
+func ParseTopicOverrides(overrides string, columns ...string) (map[Table]string, error) {
+	result := make(map[Table]string)
+	for _, entry := range strings.Split(overrides, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("parse topic override %s failed", entry)
+		}
+		table, err := ParseTable(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		topic := strings.TrimSpace(parts[1])
+		if err := ValidateTopicTemplate(topic, columns...); err != nil {
+			return nil, errors.Wrapf(err, "invalid topic override %s", entry)
+		}
+		result[*table] = topic
+	}
+	return result, nil
+}
+
+// TableTopic returns the topic of all records of the given table.
+// It returns an error if the topic depends on values of the records.
+func (t *TopicTemplate) TableTopic(table Table) (string, error) {
+	topic, err := t.Topic(&Record{Table: table})
+	if err != nil {
+		return "", errors.Wrapf(err, "topic of %s depends on record values", table)
+	}
+	return topic, nil
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TopicTemplate", func() {
+	var record *cdc.Record
+
+	BeforeEach(func() {
+		record = jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_type": "update_after"}`)
+	})
+
+	It("returns static topic", func() {
+		topic, err := (&cdc.TopicTemplate{Template: "mytopic"}).Topic(record)
+		Expect(err).To(BeNil())
+		Expect(topic).To(Equal("mytopic"))
+	})
+
+	It("replaces database and table", func() {
+		topic, err := (&cdc.TopicTemplate{Template: "cdc.{database}.{table}"}).Topic(record)
+		Expect(err).To(BeNil())
+		Expect(topic).To(Equal("cdc.mydb.mytable"))
+	})
+
+	It("replaces event type and domain", func() {
+		topic, err := (&cdc.TopicTemplate{Template: "cdc.{domain}.{table}.{event_type}"}).Topic(record)
+		Expect(err).To(BeNil())
+		Expect(topic).To(Equal("cdc.0.mytable.update_after"))
+	})
+
+	It("uses override of table", func() {
+		template := &cdc.TopicTemplate{
+			Template: "cdc.{database}.{table}",
+			Overrides: map[cdc.Table]string{
+				{Database: "mydb", Name: "mytable"}: "special.{event_type}",
+			},
+		}
+		topic, err := template.Topic(record)
+		Expect(err).To(BeNil())
+		Expect(topic).To(Equal("special.update_after"))
+	})
+
+	It("replaces invalid characters", func() {
+		record.Table.Name = "my table$"
+		topic, err := (&cdc.TopicTemplate{Template: "cdc.{table}"}).Topic(record)
+		Expect(err).To(BeNil())
+		Expect(topic).To(Equal("cdc.my_table_"))
+	})
+
+	It("returns error if record can not be decoded", func() {
+		_, err := (&cdc.TopicTemplate{Template: "cdc.{event_type}"}).Topic(jsonRecord("banana"))
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error if value is missing", func() {
+		_, err := (&cdc.TopicTemplate{Template: "cdc.{event_type}"}).Topic(jsonRecord(`{"domain": 0}`))
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("replaces column values", func() {
+		topic, err := (&cdc.TopicTemplate{Template: "orders.tenant-{tenant_id}"}).Topic(jsonRecord(`{"tenant_id": 42}`))
+		Expect(err).To(BeNil())
+		Expect(topic).To(Equal("orders.tenant-42"))
+	})
+
+	It("replaces mapped column values", func() {
+		topicTemplate := &cdc.TopicTemplate{
+			Template: "orders.tenant-{tenant_id}",
+			Mappings: map[string]map[string]string{"tenant_id": {"42": "acme"}},
+		}
+		topic, err := topicTemplate.Topic(jsonRecord(`{"tenant_id": 42}`))
+		Expect(err).To(BeNil())
+		Expect(topic).To(Equal("orders.tenant-acme"))
+		_, err = topicTemplate.Topic(jsonRecord(`{"tenant_id": 7}`))
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error for null value without default", func() {
+		_, err := (&cdc.TopicTemplate{Template: "orders.tenant-{tenant_id}"}).Topic(jsonRecord(`{"tenant_id": null}`))
+		Expect(err).NotTo(BeNil())
+	})
+
+	Context("with default", func() {
+		var topicTemplate *cdc.TopicTemplate
+
+		BeforeEach(func() {
+			topicTemplate = &cdc.TopicTemplate{
+				Template: "orders.tenant-{tenant_id}",
+				Mappings: map[string]map[string]string{"tenant_id": {"42": "acme"}},
+				Default:  "{database}.{table}.dlq",
+			}
+		})
+
+		for name, data := range map[string]string{
+			"unmapped": `{"tenant_id": 7}`,
+			"null":     `{"tenant_id": null}`,
+			"missing":  `{"id": 1}`,
+		} {
+			data := data
+			It("routes "+name+" values to the default topic", func() {
+				topic, err := topicTemplate.Topic(jsonRecord(data))
+				Expect(err).To(BeNil())
+				Expect(topic).To(Equal("mydb.mytable.dlq"))
+			})
+		}
+
+		It("returns error if record can not be decoded", func() {
+			_, err := topicTemplate.Topic(jsonRecord("banana"))
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
+
+var _ = Describe("ParseTopicMappings", func() {
+	It("parses mappings", func() {
+		mappings, err := cdc.ParseTopicMappings("tenant_id=42:acme+7:globex, region=eu-1:europe", "tenant_id", "region")
+		Expect(err).To(BeNil())
+		Expect(mappings).To(Equal(map[string]map[string]string{
+			"tenant_id": {"42": "acme", "7": "globex"},
+			"region":    {"eu-1": "europe"},
+		}))
+	})
+
+	It("returns error for invalid mappings", func() {
+		for _, mappings := range []string{"tenant_id", "tenant_id=42", "tenant_id=:acme", "tenant-id=42:acme"} {
+			_, err := cdc.ParseTopicMappings(mappings, "tenant_id")
+			Expect(err).NotTo(BeNil(), mappings)
+		}
+	})
+
+	It("returns error for undeclared column", func() {
+		_, err := cdc.ParseTopicMappings("tenant_id=42:acme")
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("ValidateTopicTemplate", func() {
+	It("accepts built in placeholders", func() {
+		Expect(cdc.ValidateTopicTemplate("cdc.{database}.{table}.{event_type}.{domain}.{server_id}")).To(BeNil())
+	})
+
+	It("accepts declared columns", func() {
+		Expect(cdc.ValidateTopicTemplate("orders.tenant-{tenant_id}", "tenant_id")).To(BeNil())
+	})
+
+	It("returns error for unknown placeholder", func() {
+		Expect(cdc.ValidateTopicTemplate("cdc.{database}.{tabel}", "tenant_id")).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("ParseTopicColumns", func() {
+	It("parses list", func() {
+		columns, err := cdc.ParseTopicColumns("tenant_id, region")
+		Expect(err).To(BeNil())
+		Expect(columns).To(Equal([]string{"tenant_id", "region"}))
+	})
+
+	It("returns error for invalid column", func() {
+		_, err := cdc.ParseTopicColumns("tenant-id")
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("ParseTopicOverrides", func() {
+
+	It("parses list", func() {
+		overrides, err := cdc.ParseTopicOverrides("mydb.a=topic-a, mydb.b=topic-b")
+		Expect(err).To(BeNil())
+		Expect(overrides).To(Equal(map[cdc.Table]string{
+			{Database: "mydb", Name: "a"}: "topic-a",
+			{Database: "mydb", Name: "b"}: "topic-b",
+		}))
+	})
+
+	It("returns error for missing topic", func() {
+		_, err := cdc.ParseTopicOverrides("mydb.a")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error for malformed placeholder", func() {
+		_, err := cdc.ParseTopicOverrides("mydb.a={banana")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error for unknown placeholder", func() {
+		_, err := cdc.ParseTopicOverrides("mydb.a={banana}")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("accepts declared columns", func() {
+		overrides, err := cdc.ParseTopicOverrides("mydb.a=a.{tenant_id}", "tenant_id")
+		Expect(err).To(BeNil())
+		Expect(overrides).To(HaveKeyWithValue(cdc.Table{Database: "mydb", Name: "a"}, "a.{tenant_id}"))
+	})
+})
+
+var _ = Describe("TopicTemplate.TableTopic", func() {
+	It("returns topic of table", func() {
+		topicTemplate := &cdc.TopicTemplate{Template: "cdc.{database}.{table}"}
+		topic, err := topicTemplate.TableTopic(cdc.Table{Database: "shop", Name: "orders"})
+		Expect(err).To(BeNil())
+		Expect(topic).To(Equal("cdc.shop.orders"))
+	})
+
+	It("returns error if topic depends on values", func() {
+		topicTemplate := &cdc.TopicTemplate{Template: "cdc.{database}.{table}.{event_type}"}
+		_, err := topicTemplate.TableTopic(cdc.Table{Database: "shop", Name: "orders"})
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,32 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Transcoder converts records into the given format (JSON or AVRO).
+// The conversion uses the schema Maxscale sends at the start of every stream.
+type Transcoder struct {
+	Format string
+}
+
+// Process converts the record if its format differs
+func (t *Transcoder) Process(record *Record) ([]*Record, error) {
+	if record.Format == t.Format {
+		return []*Record{record}, nil
+	}
+	values, err := record.Values()
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode %s record failed", record.Format)
+	}
+	result := *record
+	result.Format = t.Format
+	if err := result.SetValues(values); err != nil {
+		return nil, errors.Wrapf(err, "encode %s record failed", t.Format)
+	}
+	return []*Record{&result}, nil
+}
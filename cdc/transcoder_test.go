@@ -0,0 +1,108 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"bytes"
+
+	"github.com/bborbe/kafka-maxscale-cdc-connector/avro"
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const namesSchema = `{"namespace": "MaxScaleChangeDataSchema.avro", "type": "record", "name": "ChangeRecord", "fields": [
+	{"name": "domain", "type": "int"},
+	{"name": "server_id", "type": "int"},
+	{"name": "sequence", "type": "int"},
+	{"name": "event_number", "type": "int"},
+	{"name": "timestamp", "type": "int"},
+	{"name": "event_type", "type": {"type": "enum", "name": "EVENT_TYPES", "symbols": ["insert", "update_before", "update_after", "delete"]}},
+	{"name": "id", "type": "int"},
+	{"name": "name", "type": ["null", "string"]}
+]}`
+
+func namesRecord(format string, values map[string]interface{}) *cdc.Record {
+	schema, err := avro.ParseSchema([]byte(namesSchema))
+	Expect(err).To(BeNil())
+	record := &cdc.Record{
+		Table:  cdc.Table{Database: "test", Name: "names"},
+		Format: format,
+		Schema: schema,
+	}
+	Expect(record.SetValues(values)).To(BeNil())
+	return record
+}
+
+var _ = Describe("Transcoder", func() {
+	var values map[string]interface{}
+
+	BeforeEach(func() {
+		values = map[string]interface{}{
+			"domain":       0,
+			"server_id":    1,
+			"sequence":     58,
+			"event_number": 1,
+			"timestamp":    1541348151,
+			"event_type":   "insert",
+			"id":           4,
+			"name":         "Hello",
+		}
+	})
+
+	It("converts JSON to AVRO", func() {
+		record := namesRecord("JSON", values)
+		Expect(string(record.Data)).To(Equal(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "timestamp": 1541348151, "event_type": "insert", "id": 4, "name": "Hello"}` + "\n"))
+		transcoder := &cdc.Transcoder{Format: "AVRO"}
+		records, err := transcoder.Process(record)
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Format).To(Equal("AVRO"))
+		Expect(records[0].Table).To(Equal(record.Table))
+		decoded, err := avro.Decode(record.Schema, bytes.NewReader(records[0].Data))
+		Expect(err).To(BeNil())
+		Expect(decoded).To(HaveKeyWithValue("sequence", int32(58)))
+		Expect(decoded).To(HaveKeyWithValue("name", "Hello"))
+		Expect(decoded).To(HaveKeyWithValue("event_type", "insert"))
+	})
+
+	It("converts AVRO to JSON", func() {
+		values["name"] = nil
+		record := namesRecord("AVRO", values)
+		transcoder := &cdc.Transcoder{Format: "JSON"}
+		records, err := transcoder.Process(record)
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Format).To(Equal("JSON"))
+		Expect(string(records[0].Data)).To(Equal(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "timestamp": 1541348151, "event_type": "insert", "id": 4, "name": null}` + "\n"))
+		Expect(record.Format).To(Equal("AVRO"))
+	})
+
+	It("returns record unchanged if format matches", func() {
+		record := namesRecord("JSON", values)
+		transcoder := &cdc.Transcoder{Format: "JSON"}
+		records, err := transcoder.Process(record)
+		Expect(err).To(BeNil())
+		Expect(records).To(Equal([]*cdc.Record{record}))
+	})
+
+	It("returns error for JSON to AVRO without schema", func() {
+		record := namesRecord("JSON", values)
+		record.Schema = nil
+		transcoder := &cdc.Transcoder{Format: "AVRO"}
+		_, err := transcoder.Process(record)
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("returns error if record does not match schema", func() {
+		record := jsonRecord(`{"domain": 0, "event_type": "banana"}`)
+		schema, err := avro.ParseSchema([]byte(namesSchema))
+		Expect(err).To(BeNil())
+		record.Schema = schema
+		transcoder := &cdc.Transcoder{Format: "AVRO"}
+		_, err = transcoder.Process(record)
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,58 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"github.com/golang/glog"
+)
+
+// UpdateMerger merges the update_before record into the following update_after record.
+// Maxscale writes both images of an update as consecutive events of the transaction.
+type UpdateMerger struct {
+	before *Record
+}
+
+// Process holds back update_before records and sets them as Before of the matching update_after record.
+// An update_before without matching update_after is sent unchanged.
+func (u *UpdateMerger) Process(record *Record) ([]*Record, error) {
+	values, err := record.Values()
+	if err != nil {
+		return []*Record{record}, nil
+	}
+	var result []*Record
+	if u.before != nil {
+		if values["event_type"] == "update_after" && isBeforeImage(u.before, values) {
+			merged := *record
+			merged.Before = u.before
+			u.before = nil
+			return []*Record{&merged}, nil
+		}
+		glog.V(2).Infof("update_after of %s missing", record.Table)
+		result = append(result, u.before)
+		u.before = nil
+	}
+	if values["event_type"] == "update_before" {
+		u.before = record
+		return result, nil
+	}
+	return append(result, record), nil
+}
+
+// isBeforeImage returns true if the record is the event before the given values in the same transaction
+func isBeforeImage(before *Record, values map[string]interface{}) bool {
+	beforeValues, err := before.Values()
+	if err != nil {
+		return false
+	}
+	beforeGTID, err := GTIDFromValues(beforeValues)
+	if err != nil {
+		return false
+	}
+	gtid, err := GTIDFromValues(values)
+	if err != nil {
+		return false
+	}
+	return *beforeGTID == *gtid && eventNumber(beforeValues)+1 == eventNumber(values)
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2018 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpdateMerger", func() {
+	var merger *cdc.UpdateMerger
+
+	BeforeEach(func() {
+		merger = &cdc.UpdateMerger{}
+	})
+
+	It("merges update_before into update_after", func() {
+		before := jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "event_type": "update_before", "id": 4, "name": "a"}`)
+		records, err := merger.Process(before)
+		Expect(err).To(BeNil())
+		Expect(records).To(BeEmpty())
+		records, err = merger.Process(jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2, "event_type": "update_after", "id": 4, "name": "b"}`))
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Before).To(Equal(before))
+	})
+
+	It("sends update_before without matching update_after", func() {
+		before := jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "event_type": "update_before", "id": 4}`)
+		_, err := merger.Process(before)
+		Expect(err).To(BeNil())
+		insert := jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 2, "event_type": "insert", "id": 5}`)
+		records, err := merger.Process(insert)
+		Expect(err).To(BeNil())
+		Expect(records).To(Equal([]*cdc.Record{before, insert}))
+	})
+
+	It("does not merge images of different transactions", func() {
+		_, err := merger.Process(jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 58, "event_number": 1, "event_type": "update_before", "id": 4}`))
+		Expect(err).To(BeNil())
+		records, err := merger.Process(jsonRecord(`{"domain": 0, "server_id": 1, "sequence": 59, "event_number": 2, "event_type": "update_after", "id": 4}`))
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(2))
+		Expect(records[1].Before).To(BeNil())
+	})
+})
@@ -37,10 +37,10 @@ func main() {
 		Format:   "JSON",
 	}
 
-	ch := make(chan []byte, runtime.NumCPU())
+	ch := make(chan *cdc.Record, runtime.NumCPU())
 	go func() {
-		for line := range ch {
-			os.Stdout.Write(line)
+		for record := range ch {
+			os.Stdout.Write(record.Data)
 		}
 	}()
 
@@ -10,6 +10,7 @@ import (
 	"os/signal"
 	"runtime"
 	"syscall"
+	"time"
 
 	flag "github.com/bborbe/flagenv"
 	"github.com/bborbe/kafka-maxscale-cdc-connector/cdc"
@@ -31,13 +32,58 @@ func main() {
 	flag.StringVar(&app.CdcPassword, "cdc-password", "", "cdc password")
 	flag.StringVar(&app.CdcDatabase, "cdc-database", "", "cdc database")
 	flag.StringVar(&app.CdcTable, "cdc-table", "", "cdc table")
-	flag.StringVar(&app.CdcUUID, "cdc-uuid", uuid.New().String(), "cdc client identifier uuid")
+	flag.StringVar(&app.CdcTables, "cdc-tables", "", "comma separated list of cdc tables in format DATABASE.TABLE, wildcards like shop.order_* are allowed")
+	flag.StringVar(&app.CdcExcludeTables, "cdc-exclude-tables", "", "comma separated list of table patterns to exclude from discovery")
+	flag.StringVar(&app.CdcAvroDir, "cdc-avro-dir", "", "avrodir of the Maxscale avrorouter used for table discovery")
+	flag.DurationVar(&app.CdcDiscoveryInterval, "cdc-discovery-interval", time.Minute, "interval to discover new tables")
+	flag.StringVar(&app.CdcUUID, "cdc-uuid", "", "cdc client identifier uuid, random if empty, required by checkpoint-store kafka")
+	flag.StringVar(&app.CdcGTID, "cdc-gtid", "", "gtid position to start from, e.g. 0-1-58,1-2-10, default is the stored position")
 	flag.StringVar(&app.CdcFormat, "cdc-format", "JSON", "cdc output format (JSON|AVRO)")
+	flag.StringVar(&app.SchemaRegistryURL, "schema-registry-url", "", "url of the Confluent Schema Registry, requires kafka-format AVRO")
+	flag.StringVar(&app.SchemaRegistrySubjectNameStrategy, "schema-registry-subject-name-strategy", cdc.TopicNameStrategy, "subject name strategy (TopicNameStrategy|RecordNameStrategy|TopicRecordNameStrategy|TableNameStrategy)")
 	flag.StringVar(&app.KafkaBrokers, "kafka-brokers", "", "kafka brokers")
-	flag.StringVar(&app.KafkaTopic, "kafka-topic", "", "kafka topic")
+	flag.StringVar(&app.KafkaTopic, "kafka-topic", "", "kafka topic, placeholders {database}, {table}, {event_type}, {domain} and {server_id} are replaced")
+	flag.StringVar(&app.KafkaTopicOverrides, "kafka-topic-overrides", "", "comma separated list of DATABASE.TABLE=TOPIC to override the topic of a table")
+	flag.StringVar(&app.OutboxTables, "outbox-tables", "", "comma separated list of tables or patterns of transactional outbox tables")
+	flag.StringVar(&app.OutboxTopic, "outbox-topic", cdc.DefaultOutboxTopic, "topic template of outbox events")
+	flag.BoolVar(&app.OutboxTombstones, "outbox-tombstones", false, "send a tombstone for the aggregate id of deleted outbox rows")
+	flag.StringVar(&app.KafkaTopicColumns, "kafka-topic-columns", "", "comma separated list of columns that topics can use as placeholders, e.g. tenant_id")
+	flag.StringVar(&app.KafkaTopicMappings, "kafka-topic-mappings", "", "comma separated list of PLACEHOLDER=VALUE:NAME+VALUE:NAME to replace values in topics, e.g. tenant_id=42:acme")
+	flag.StringVar(&app.KafkaTopicDefault, "kafka-topic-default", "", "topic of records with missing, null or unmapped placeholder values, e.g. a dead letter topic")
+	flag.StringVar(&app.KafkaKey, "kafka-key", cdc.KeyFormatGTID, "format of the message key (gtid|string|json|avro), all except gtid use the primary key columns")
+	flag.StringVar(&app.KafkaKeyColumns, "kafka-key-columns", "", "comma separated list of DATABASE.TABLE=COLUMN+COLUMN, required for each table with primary key -kafka-key")
+	flag.StringVar(&app.KafkaTombstones, "kafka-tombstones", "", "comma separated list of tables or patterns that send a tombstone after each delete, requires kafka-key")
+	flag.BoolVar(&app.KafkaTransactions, "kafka-transactions", false, "send all events of a transaction as one batch and write the checkpoint after it")
+	flag.DurationVar(&app.KafkaTransactionTimeout, "kafka-transaction-timeout", time.Second, "send the events of a transaction without end marker if no further event arrived within the given duration")
+	flag.BoolVar(&app.KafkaTransactionEnd, "kafka-transaction-end", false, "send a transaction end marker with the event count, requires kafka-transactions and kafka-format JSON")
+	flag.BoolVar(&app.KafkaAsync, "kafka-async", false, "send with an async producer without waiting for each acknowledgement")
+	flag.IntVar(&app.KafkaBatchSize, "kafka-batch-size", 0, "number of messages that trigger a flush of the async producer, requires -kafka-linger")
+	flag.DurationVar(&app.KafkaLinger, "kafka-linger", 0, "time the async producer waits for more messages of a batch")
+	flag.IntVar(&app.KafkaMaxInFlight, "kafka-max-in-flight", 1, "requests per broker without waiting for the response, retries are disabled for more than one")
+	flag.StringVar(&app.KafkaCompression, "kafka-compression", "none", "compression of the async producer (none|gzip|snappy|lz4)")
+	flag.StringVar(&app.KafkaEnvelope, "kafka-envelope", cdc.EnvelopeNone, "envelope of the message value (none|debezium|cloudevents), requires kafka-format JSON except binary cloudevents")
+	flag.StringVar(&app.KafkaCloudEventsMode, "kafka-cloudevents-mode", cdc.CloudEventsStructured, "content mode of the cloudevents envelope (structured|binary)")
+	flag.StringVar(&app.DropEventTypes, "drop-event-types", "", "comma separated list of PATTERN=TYPE+TYPE, e.g. shop.*=update_before+delete")
+	flag.StringVar(&app.IncludeColumns, "include-columns", "", "comma separated list of PATTERN=COLUMN+COLUMN, only these columns are sent")
+	flag.StringVar(&app.ExcludeColumns, "exclude-columns", "", "comma separated list of PATTERN=COLUMN+COLUMN, these columns are not sent")
+	flag.StringVar(&app.RowFilters, "row-filters", "", `semicolon separated list of PATTERN=EXPRESSION, e.g. shop.orders=tenant_id == 42 && status != "draft"`)
+	flag.StringVar(&app.MaskColumns, "mask-columns", "", "comma separated list of PATTERN=COLUMN:ACTION+COLUMN:ACTION, actions are redact, truncate:LENGTH, hash and tokenize")
+	flag.StringVar(&app.MaskKey, "mask-key", "", "secret key of hash and tokenize, at least 16 characters")
+	flag.BoolVar(&app.KafkaHeaders, "kafka-headers", false, "add database, table, version, event type, gtid, event number, uuid and host as message headers")
+	flag.StringVar(&app.KafkaSchemaTopic, "kafka-schema-topic", "", "topic new table schema versions are published to, keyed by DATABASE.TABLE.VERSION")
+	flag.StringVar(&app.CheckpointStore, "checkpoint-store", cdc.CheckpointStoreFile, "backend that stores the last gtid of each table (file|kafka|memory)")
+	flag.StringVar(&app.CheckpointTopic, "checkpoint-topic", "", "compacted topic of the kafka checkpoint store")
+	flag.IntVar(&app.CheckpointFlushEvents, "checkpoint-flush-events", 0, "write the checkpoint every N records")
+	flag.DurationVar(&app.CheckpointFlushInterval, "checkpoint-flush-interval", 0, "write pending checkpoints at the latest after the given duration")
+	flag.BoolVar(&app.CheckpointFlushTransaction, "checkpoint-flush-transaction", false, "write the checkpoint at the end of each transaction")
+	flag.StringVar(&app.GTIDRecovery, "gtid-recovery", cdc.GTIDRecoveryNone, "read the start gtid from the last messages in kafka (none|fallback|kafka|check)")
+	flag.StringVar(&app.KafkaFormat, "kafka-format", "", "format written to kafka (JSON|AVRO), default is cdc-format")
 
 	_ = flag.Set("logtostderr", "true")
 	flag.Parse()
+	if app.CdcUUID == "" && app.CheckpointStore != cdc.CheckpointStoreKafka {
+		app.CdcUUID = uuid.New().String()
+	}
 
 	glog.V(0).Infof("Parameter CdcHost: %s", app.CdcHost)
 	glog.V(0).Infof("Parameter CdcPort: %d", app.CdcPort)
@@ -45,10 +91,52 @@ func main() {
 	glog.V(0).Infof("Parameter CdcPassword-Length: %d", len(app.CdcPassword))
 	glog.V(0).Infof("Parameter CdcDatabase: %s", app.CdcDatabase)
 	glog.V(0).Infof("Parameter CdcTable: %s", app.CdcTable)
+	glog.V(0).Infof("Parameter CdcTables: %s", app.CdcTables)
+	glog.V(0).Infof("Parameter CdcExcludeTables: %s", app.CdcExcludeTables)
+	glog.V(0).Infof("Parameter CdcAvroDir: %s", app.CdcAvroDir)
+	glog.V(0).Infof("Parameter CdcDiscoveryInterval: %v", app.CdcDiscoveryInterval)
 	glog.V(0).Infof("Parameter CdcUUID: %s", app.CdcUUID)
+	glog.V(0).Infof("Parameter CdcGTID: %s", app.CdcGTID)
 	glog.V(0).Infof("Parameter CdcFormat: %s", app.CdcFormat)
+	glog.V(0).Infof("Parameter SchemaRegistryURL: %s", app.SchemaRegistryURL)
+	glog.V(0).Infof("Parameter SchemaRegistrySubjectNameStrategy: %s", app.SchemaRegistrySubjectNameStrategy)
 	glog.V(0).Infof("Parameter KafkaBrokers: %s", app.KafkaBrokers)
 	glog.V(0).Infof("Parameter KafkaTopic: %s", app.KafkaTopic)
+	glog.V(0).Infof("Parameter KafkaTopicOverrides: %s", app.KafkaTopicOverrides)
+	glog.V(0).Infof("Parameter OutboxTables: %s", app.OutboxTables)
+	glog.V(0).Infof("Parameter OutboxTopic: %s", app.OutboxTopic)
+	glog.V(0).Infof("Parameter OutboxTombstones: %v", app.OutboxTombstones)
+	glog.V(0).Infof("Parameter KafkaTopicColumns: %s", app.KafkaTopicColumns)
+	glog.V(0).Infof("Parameter KafkaTopicMappings: %s", app.KafkaTopicMappings)
+	glog.V(0).Infof("Parameter KafkaTopicDefault: %s", app.KafkaTopicDefault)
+	glog.V(0).Infof("Parameter KafkaFormat: %s", app.KafkaFormat)
+	glog.V(0).Infof("Parameter KafkaKey: %s", app.KafkaKey)
+	glog.V(0).Infof("Parameter KafkaKeyColumns: %s", app.KafkaKeyColumns)
+	glog.V(0).Infof("Parameter KafkaTombstones: %s", app.KafkaTombstones)
+	glog.V(0).Infof("Parameter KafkaTransactions: %v", app.KafkaTransactions)
+	glog.V(0).Infof("Parameter KafkaTransactionTimeout: %v", app.KafkaTransactionTimeout)
+	glog.V(0).Infof("Parameter KafkaTransactionEnd: %v", app.KafkaTransactionEnd)
+	glog.V(0).Infof("Parameter KafkaAsync: %v", app.KafkaAsync)
+	glog.V(0).Infof("Parameter KafkaBatchSize: %d", app.KafkaBatchSize)
+	glog.V(0).Infof("Parameter KafkaLinger: %v", app.KafkaLinger)
+	glog.V(0).Infof("Parameter KafkaMaxInFlight: %d", app.KafkaMaxInFlight)
+	glog.V(0).Infof("Parameter KafkaCompression: %s", app.KafkaCompression)
+	glog.V(0).Infof("Parameter KafkaEnvelope: %s", app.KafkaEnvelope)
+	glog.V(0).Infof("Parameter KafkaCloudEventsMode: %s", app.KafkaCloudEventsMode)
+	glog.V(0).Infof("Parameter KafkaHeaders: %v", app.KafkaHeaders)
+	glog.V(0).Infof("Parameter DropEventTypes: %s", app.DropEventTypes)
+	glog.V(0).Infof("Parameter IncludeColumns: %s", app.IncludeColumns)
+	glog.V(0).Infof("Parameter ExcludeColumns: %s", app.ExcludeColumns)
+	glog.V(0).Infof("Parameter RowFilters: %s", app.RowFilters)
+	glog.V(0).Infof("Parameter MaskColumns: %s", app.MaskColumns)
+	glog.V(0).Infof("Parameter MaskKey-Length: %d", len(app.MaskKey))
+	glog.V(0).Infof("Parameter KafkaSchemaTopic: %s", app.KafkaSchemaTopic)
+	glog.V(0).Infof("Parameter GTIDRecovery: %s", app.GTIDRecovery)
+	glog.V(0).Infof("Parameter CheckpointStore: %s", app.CheckpointStore)
+	glog.V(0).Infof("Parameter CheckpointTopic: %s", app.CheckpointTopic)
+	glog.V(0).Infof("Parameter CheckpointFlushEvents: %d", app.CheckpointFlushEvents)
+	glog.V(0).Infof("Parameter CheckpointFlushInterval: %v", app.CheckpointFlushInterval)
+	glog.V(0).Infof("Parameter CheckpointFlushTransaction: %v", app.CheckpointFlushTransaction)
 	glog.V(0).Infof("Parameter Port: %d", app.Port)
 	glog.V(0).Infof("Parameter DataDir: %s", app.DataDir)
 
@@ -9,12 +9,12 @@ import (
 )
 
 type Reader struct {
-	ReadStub        func(context.Context, *cdc.GTID, chan<- []byte) error
+	ReadStub        func(context.Context, *cdc.GTID, chan<- *cdc.Record) error
 	readMutex       sync.RWMutex
 	readArgsForCall []struct {
 		arg1 context.Context
 		arg2 *cdc.GTID
-		arg3 chan<- []byte
+		arg3 chan<- *cdc.Record
 	}
 	readReturns struct {
 		result1 error
@@ -26,13 +26,13 @@ type Reader struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *Reader) Read(arg1 context.Context, arg2 *cdc.GTID, arg3 chan<- []byte) error {
+func (fake *Reader) Read(arg1 context.Context, arg2 *cdc.GTID, arg3 chan<- *cdc.Record) error {
 	fake.readMutex.Lock()
 	ret, specificReturn := fake.readReturnsOnCall[len(fake.readArgsForCall)]
 	fake.readArgsForCall = append(fake.readArgsForCall, struct {
 		arg1 context.Context
 		arg2 *cdc.GTID
-		arg3 chan<- []byte
+		arg3 chan<- *cdc.Record
 	}{arg1, arg2, arg3})
 	fake.recordInvocation("Read", []interface{}{arg1, arg2, arg3})
 	fake.readMutex.Unlock()
@@ -52,13 +52,13 @@ func (fake *Reader) ReadCallCount() int {
 	return len(fake.readArgsForCall)
 }
 
-func (fake *Reader) ReadCalls(stub func(context.Context, *cdc.GTID, chan<- []byte) error) {
+func (fake *Reader) ReadCalls(stub func(context.Context, *cdc.GTID, chan<- *cdc.Record) error) {
 	fake.readMutex.Lock()
 	defer fake.readMutex.Unlock()
 	fake.ReadStub = stub
 }
 
-func (fake *Reader) ReadArgsForCall(i int) (context.Context, *cdc.GTID, chan<- []byte) {
+func (fake *Reader) ReadArgsForCall(i int) (context.Context, *cdc.GTID, chan<- *cdc.Record) {
 	fake.readMutex.RLock()
 	defer fake.readMutex.RUnlock()
 	argsForCall := fake.readArgsForCall[i]
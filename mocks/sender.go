@@ -9,11 +9,11 @@ import (
 )
 
 type Sender struct {
-	SendStub        func(context.Context, <-chan []byte) error
+	SendStub        func(context.Context, <-chan *cdc.Record) error
 	sendMutex       sync.RWMutex
 	sendArgsForCall []struct {
 		arg1 context.Context
-		arg2 <-chan []byte
+		arg2 <-chan *cdc.Record
 	}
 	sendReturns struct {
 		result1 error
@@ -25,12 +25,12 @@ type Sender struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *Sender) Send(arg1 context.Context, arg2 <-chan []byte) error {
+func (fake *Sender) Send(arg1 context.Context, arg2 <-chan *cdc.Record) error {
 	fake.sendMutex.Lock()
 	ret, specificReturn := fake.sendReturnsOnCall[len(fake.sendArgsForCall)]
 	fake.sendArgsForCall = append(fake.sendArgsForCall, struct {
 		arg1 context.Context
-		arg2 <-chan []byte
+		arg2 <-chan *cdc.Record
 	}{arg1, arg2})
 	fake.recordInvocation("Send", []interface{}{arg1, arg2})
 	fake.sendMutex.Unlock()
@@ -50,13 +50,13 @@ func (fake *Sender) SendCallCount() int {
 	return len(fake.sendArgsForCall)
 }
 
-func (fake *Sender) SendCalls(stub func(context.Context, <-chan []byte) error) {
+func (fake *Sender) SendCalls(stub func(context.Context, <-chan *cdc.Record) error) {
 	fake.sendMutex.Lock()
 	defer fake.sendMutex.Unlock()
 	fake.SendStub = stub
 }
 
-func (fake *Sender) SendArgsForCall(i int) (context.Context, <-chan []byte) {
+func (fake *Sender) SendArgsForCall(i int) (context.Context, <-chan *cdc.Record) {
 	fake.sendMutex.RLock()
 	defer fake.sendMutex.RUnlock()
 	argsForCall := fake.sendArgsForCall[i]